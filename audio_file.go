@@ -0,0 +1,468 @@
+package gostream
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.viam.com/utils"
+)
+
+// A PCMSampleFormat describes how each sample of raw PCM audio is encoded.
+type PCMSampleFormat int
+
+// The set of supported PCM sample formats.
+const (
+	PCMSampleFormatInt16 PCMSampleFormat = iota
+	PCMSampleFormatFloat32
+)
+
+// bytesPerSample returns how many bytes a single sample of one channel takes up.
+func (f PCMSampleFormat) bytesPerSample() int {
+	if f == PCMSampleFormatFloat32 {
+		return 4
+	}
+	return 2
+}
+
+// PCMFormat describes raw interleaved, little-endian PCM audio.
+type PCMFormat struct {
+	SampleFormat PCMSampleFormat
+	SampleRate   int
+	Channels     int
+}
+
+// audioFileChunkDuration is how much audio is produced per read from a file or
+// raw PCM reader.
+const audioFileChunkDuration = AudioFrameDuration20ms
+
+type pcmAudioReader struct {
+	mu     sync.Mutex
+	r      io.Reader
+	closer io.Closer
+	format PCMFormat
+	paced  bool
+	// rewind, if set, is called at the end of the audio in order to loop it.
+	rewind func() error
+
+	start       time.Time
+	samplesRead int64
+}
+
+// NewPCMAudioSource returns an audio source that reads raw interleaved, little-endian
+// PCM audio of the given format from r. If paced is set, audio is produced no faster
+// than real-time which is useful when r is not a live source itself (e.g. a file). If
+// r is an io.Closer, it is closed when the source is closed.
+func NewPCMAudioSource(r io.Reader, format PCMFormat, paced bool) (AudioSource, error) {
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, errors.Errorf("invalid PCM format %+v", format)
+	}
+	reader := &pcmAudioReader{
+		r:      r,
+		format: format,
+		paced:  paced,
+	}
+	if closer, ok := r.(io.Closer); ok {
+		reader.closer = closer
+	}
+	return NewAudioSource(reader, reader.properties()), nil
+}
+
+func (pr *pcmAudioReader) properties() prop.Audio {
+	return prop.Audio{
+		ChannelCount:  pr.format.Channels,
+		Latency:       audioFileChunkDuration,
+		SampleRate:    pr.format.SampleRate,
+		SampleSize:    pr.format.SampleFormat.bytesPerSample() * 8,
+		IsFloat:       pr.format.SampleFormat == PCMSampleFormatFloat32,
+		IsInterleaved: true,
+	}
+}
+
+// Read returns the next chunk of audio from the underlying reader.
+func (pr *pcmAudioReader) Read(ctx context.Context) (wave.Audio, func(), error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	frameSize := pr.format.SampleFormat.bytesPerSample() * pr.format.Channels
+	buf := make([]byte, samplesForDuration(pr.format.SampleRate, audioFileChunkDuration)*frameSize)
+	var numSamples int
+	var rewound bool
+	for {
+		n, err := io.ReadFull(pr.r, buf)
+		numSamples = n / frameSize
+		if err == nil || (errors.Is(err, io.ErrUnexpectedEOF) && numSamples != 0) {
+			break
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, nil, err
+		}
+		// only rewind once per read so that empty audio does not loop forever
+		if pr.rewind == nil || rewound {
+			return nil, nil, io.EOF
+		}
+		if err := pr.rewind(); err != nil {
+			return nil, nil, err
+		}
+		rewound = true
+	}
+
+	if pr.paced {
+		if pr.start.IsZero() {
+			pr.start = time.Now()
+		}
+		due := pr.start.Add(samplesDuration(pr.samplesRead, pr.format.SampleRate))
+		if wait := time.Until(due); wait > 0 {
+			if !utils.SelectContextOrWait(ctx, wait) {
+				return nil, nil, ctx.Err()
+			}
+		}
+	}
+	pr.samplesRead += int64(numSamples)
+
+	return decodePCM(buf[:numSamples*frameSize], pr.format, numSamples), func() {}, nil
+}
+
+// samplesDuration returns how long the given number of samples at the given sampling rate
+// last. Whole seconds are split off first so that sources read for days, such as looped
+// ones, do not overflow.
+func samplesDuration(samples int64, samplingRate int) time.Duration {
+	rate := int64(samplingRate)
+	return time.Duration(samples/rate)*time.Second + time.Duration(samples%rate)*time.Second/time.Duration(rate)
+}
+
+// Close closes the underlying reader, if it can be closed.
+func (pr *pcmAudioReader) Close(_ context.Context) error {
+	if pr.closer == nil {
+		return nil
+	}
+	return pr.closer.Close()
+}
+
+// decodePCM converts raw interleaved, little-endian PCM bytes into audio.
+func decodePCM(data []byte, format PCMFormat, numSamples int) wave.Audio {
+	info := wave.ChunkInfo{
+		Len:          numSamples,
+		Channels:     format.Channels,
+		SamplingRate: format.SampleRate,
+	}
+	if format.SampleFormat == PCMSampleFormatFloat32 {
+		chunk := wave.NewFloat32Interleaved(info)
+		for i := range chunk.Data {
+			chunk.Data[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+		}
+		return chunk
+	}
+	chunk := wave.NewInt16Interleaved(info)
+	for i := range chunk.Data {
+		chunk.Data[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return chunk
+}
+
+// encodePCM appends the given audio to buf as raw interleaved, little-endian PCM
+// bytes of the given sample format.
+func encodePCM(buf []byte, chunk wave.Audio, sampleFormat PCMSampleFormat) []byte {
+	info := chunk.ChunkInfo()
+	switch c := chunk.(type) {
+	case *wave.Int16Interleaved:
+		if sampleFormat == PCMSampleFormatInt16 {
+			for _, sample := range c.Data[:info.Len*info.Channels] {
+				buf = binary.LittleEndian.AppendUint16(buf, uint16(sample))
+			}
+			return buf
+		}
+	case *wave.Float32Interleaved:
+		if sampleFormat == PCMSampleFormatFloat32 {
+			for _, sample := range c.Data[:info.Len*info.Channels] {
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(sample))
+			}
+			return buf
+		}
+	}
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			sample := chunk.At(i, ch)
+			if sampleFormat == PCMSampleFormatFloat32 {
+				asFloat := float32(wave.Float32SampleFormat.Convert(sample).(wave.Float32Sample))
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(asFloat))
+			} else {
+				asInt := int16(wave.Int16SampleFormat.Convert(sample).(wave.Int16Sample))
+				buf = binary.LittleEndian.AppendUint16(buf, uint16(asInt))
+			}
+		}
+	}
+	return buf
+}
+
+// The WAV format codes that we support.
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// NewWAVAudioSource returns an audio source that plays back the WAV file at the given
+// path in real-time. 16-bit PCM and 32-bit float WAV files are supported at any
+// sample rate and channel count. If loop is set, the file starts over once it has
+// been fully read; otherwise the source returns io.EOF.
+func NewWAVAudioSource(path string, loop bool) (AudioSource, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	format, data, err := readWAVHeader(f)
+	if err != nil {
+		return nil, multierr.Combine(err, f.Close())
+	}
+
+	reader := &pcmAudioReader{
+		r:      data,
+		closer: f,
+		format: format,
+		paced:  true,
+	}
+	if loop {
+		reader.rewind = func() error {
+			_, err := data.Seek(0, io.SeekStart)
+			return err
+		}
+	}
+	return NewAudioSource(reader, reader.properties()), nil
+}
+
+// readWAVHeader parses the RIFF chunks of a WAV file up until the data chunk and returns
+// the format of the audio along with a reader of just the audio data.
+func readWAVHeader(f *os.File) (PCMFormat, *io.SectionReader, error) {
+	var format PCMFormat
+	var riffHeader [12]byte
+	if _, err := io.ReadFull(f, riffHeader[:]); err != nil {
+		return format, nil, errors.Wrap(err, "error reading WAV header")
+	}
+	if string(riffHeader[0:4]) != "RIFF" || string(riffHeader[8:12]) != "WAVE" {
+		return format, nil, errors.New("not a WAV file")
+	}
+
+	offset := int64(len(riffHeader))
+	var haveFormat bool
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(f, chunkHeader[:]); err != nil {
+			return format, nil, errors.Wrap(err, "error reading WAV chunk")
+		}
+		offset += int64(len(chunkHeader))
+		chunkID := string(chunkHeader[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return format, nil, errors.Errorf("invalid WAV fmt chunk size %d", chunkSize)
+			}
+			fmtChunk := make([]byte, chunkSize)
+			if _, err := io.ReadFull(f, fmtChunk); err != nil {
+				return format, nil, errors.Wrap(err, "error reading WAV fmt chunk")
+			}
+			formatCode := binary.LittleEndian.Uint16(fmtChunk[0:2])
+			if formatCode == wavFormatExtensible && chunkSize >= 40 {
+				// the actual format is the start of the sub format GUID
+				formatCode = binary.LittleEndian.Uint16(fmtChunk[24:26])
+			}
+			bitsPerSample := binary.LittleEndian.Uint16(fmtChunk[14:16])
+			switch {
+			case formatCode == wavFormatPCM && bitsPerSample == 16:
+				format.SampleFormat = PCMSampleFormatInt16
+			case formatCode == wavFormatIEEEFloat && bitsPerSample == 32:
+				format.SampleFormat = PCMSampleFormatFloat32
+			default:
+				return format, nil, errors.Errorf(
+					"unsupported WAV format %#x with %d bits per sample", formatCode, bitsPerSample)
+			}
+			format.Channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			format.SampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			if format.Channels == 0 || format.SampleRate == 0 {
+				return format, nil, errors.New("invalid WAV fmt chunk")
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return format, nil, errors.New("WAV data chunk found before fmt chunk")
+			}
+			if chunkSize == math.MaxUint32 {
+				// size unknown (e.g. a recording that was never finalized); use the rest of the file
+				stat, err := f.Stat()
+				if err != nil {
+					return format, nil, err
+				}
+				chunkSize = stat.Size() - offset
+			}
+			return format, io.NewSectionReader(f, offset, chunkSize), nil
+		default:
+			if _, err := f.Seek(chunkSize, io.SeekCurrent); err != nil {
+				return format, nil, err
+			}
+		}
+		offset += chunkSize
+		// chunks are word aligned
+		if chunkSize%2 == 1 {
+			if _, err := f.Seek(1, io.SeekCurrent); err != nil {
+				return format, nil, err
+			}
+			offset++
+		}
+	}
+}
+
+// wavHeaderSize is the size of the header written by a wavAudioSink.
+const wavHeaderSize = 44
+
+type wavAudioSink struct {
+	mu        sync.Mutex
+	w         io.WriteSeeker
+	closer    io.Closer
+	format    PCMFormat
+	formatSet bool
+	dataSize  int64
+	buf       []byte
+}
+
+// NewWAVAudioSink returns a sink that records all audio written to it as a WAV file at the
+// given path. The format of the file is determined by the first chunk written; 16-bit
+// audio is recorded as 16-bit PCM and everything else as 32-bit float. The file is
+// finalized when the sink is closed.
+func NewWAVAudioSink(path string) (AudioSink, error) {
+	//nolint:gosec
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newWAVAudioSink(f, f), nil
+}
+
+// NewWAVAudioSinkForWriter returns a sink that records all audio written to it in the
+// WAV format to the given writer. The writer must be seekable so that the header can be
+// finalized when the sink is closed. If w is an io.Closer, it is closed when the sink is closed.
+func NewWAVAudioSinkForWriter(w io.WriteSeeker) AudioSink {
+	closer, _ := w.(io.Closer)
+	return newWAVAudioSink(w, closer)
+}
+
+func newWAVAudioSink(w io.WriteSeeker, closer io.Closer) *wavAudioSink {
+	return &wavAudioSink{w: w, closer: closer}
+}
+
+// Write appends the audio chunk to the WAV data.
+func (ws *wavAudioSink) Write(_ context.Context, chunk wave.Audio) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	info := chunk.ChunkInfo()
+	if !ws.formatSet {
+		ws.format = PCMFormat{
+			SampleFormat: PCMSampleFormatFloat32,
+			SampleRate:   info.SamplingRate,
+			Channels:     info.Channels,
+		}
+		if chunk.SampleFormat() == wave.Int16SampleFormat {
+			ws.format.SampleFormat = PCMSampleFormatInt16
+		}
+		if _, err := ws.w.Write(ws.header()); err != nil {
+			return err
+		}
+		ws.formatSet = true
+	} else if info.SamplingRate != ws.format.SampleRate || info.Channels != ws.format.Channels {
+		return errors.Errorf(
+			"audio format changed from %d Hz and %d channels to %d Hz and %d channels",
+			ws.format.SampleRate, ws.format.Channels, info.SamplingRate, info.Channels)
+	}
+
+	ws.buf = encodePCM(ws.buf[:0], chunk, ws.format.SampleFormat)
+	n, err := ws.w.Write(ws.buf)
+	ws.dataSize += int64(n)
+	return err
+}
+
+// header returns a WAV header reflecting the current format and data size.
+func (ws *wavAudioSink) header() []byte {
+	formatCode := uint16(wavFormatPCM)
+	if ws.format.SampleFormat == PCMSampleFormatFloat32 {
+		formatCode = wavFormatIEEEFloat
+	}
+	bytesPerSample := ws.format.SampleFormat.bytesPerSample()
+	blockAlign := bytesPerSample * ws.format.Channels
+
+	header := make([]byte, 0, wavHeaderSize)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(wavHeaderSize-8+ws.dataSize))
+	header = append(header, "WAVE"...)
+	header = append(header, "fmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, formatCode)
+	header = binary.LittleEndian.AppendUint16(header, uint16(ws.format.Channels))
+	header = binary.LittleEndian.AppendUint32(header, uint32(ws.format.SampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(ws.format.SampleRate*blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(bytesPerSample*8))
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(ws.dataSize))
+	return header
+}
+
+// Close rewrites the header with the final sizes and closes the underlying writer.
+func (ws *wavAudioSink) Close(_ context.Context) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var err error
+	if ws.formatSet {
+		if _, seekErr := ws.w.Seek(0, io.SeekStart); seekErr != nil {
+			err = seekErr
+		} else {
+			_, err = ws.w.Write(ws.header())
+		}
+	}
+	if ws.closer != nil {
+		err = multierr.Combine(err, ws.closer.Close())
+	}
+	return err
+}
+
+type pcmAudioSink struct {
+	mu           sync.Mutex
+	w            io.Writer
+	sampleFormat PCMSampleFormat
+	buf          []byte
+}
+
+// NewPCMAudioSink returns a sink that writes all audio written to it as raw interleaved,
+// little-endian PCM of the given sample format. If w is an io.Closer, it is closed when
+// the sink is closed.
+func NewPCMAudioSink(w io.Writer, sampleFormat PCMSampleFormat) AudioSink {
+	return &pcmAudioSink{w: w, sampleFormat: sampleFormat}
+}
+
+// Write writes the audio chunk as raw PCM.
+func (ps *pcmAudioSink) Write(_ context.Context, chunk wave.Audio) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.buf = encodePCM(ps.buf[:0], chunk, ps.sampleFormat)
+	_, err := ps.w.Write(ps.buf)
+	return err
+}
+
+// Close closes the underlying writer, if it can be closed.
+func (ps *pcmAudioSink) Close(_ context.Context) error {
+	if closer, ok := ps.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
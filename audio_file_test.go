@@ -0,0 +1,73 @@
+package gostream_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+
+	"github.com/edaniels/gostream"
+)
+
+func TestWAVRoundTrip(t *testing.T) {
+	format := gostream.PCMFormat{
+		SampleFormat: gostream.PCMSampleFormatInt16,
+		SampleRate:   8000,
+		Channels:     2,
+	}
+	// 50ms of audio which does not evenly divide into chunks
+	var raw []int16
+	for i := 0; i < 400; i++ {
+		raw = append(raw, int16(i), int16(-i))
+	}
+	var rawBytes bytes.Buffer
+	pcmSink := gostream.NewPCMAudioSink(&rawBytes, gostream.PCMSampleFormatInt16)
+	test.That(t, pcmSink.Write(context.Background(), &wave.Int16Interleaved{
+		Data: raw,
+		Size: wave.ChunkInfo{Len: 400, Channels: 2, SamplingRate: 8000},
+	}), test.ShouldBeNil)
+	test.That(t, pcmSink.Close(context.Background()), test.ShouldBeNil)
+	test.That(t, rawBytes.Len(), test.ShouldEqual, 1600)
+
+	pcmSrc, err := gostream.NewPCMAudioSource(&rawBytes, format, false)
+	test.That(t, err, test.ShouldBeNil)
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+	wavSink, err := gostream.NewWAVAudioSink(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, gostream.StreamAudioSourceToSink(context.Background(), pcmSrc, wavSink), test.ShouldBeNil)
+	test.That(t, wavSink.Close(context.Background()), test.ShouldBeNil)
+	test.That(t, pcmSrc.Close(context.Background()), test.ShouldBeNil)
+
+	wavSrc, err := gostream.NewWAVAudioSource(path, false)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, wavSrc.Close(context.Background()), test.ShouldBeNil)
+	}()
+	props, err := wavSrc.(gostream.AudioPropertyProvider).MediaProperties(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.SampleRate, test.ShouldEqual, 8000)
+	test.That(t, props.ChannelCount, test.ShouldEqual, 2)
+	test.That(t, props.IsFloat, test.ShouldBeFalse)
+
+	stream, err := wavSrc.Stream(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, stream.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	var read []int16
+	for {
+		chunk, _, err := stream.Next(context.Background())
+		if err != nil {
+			test.That(t, err, test.ShouldBeError, io.EOF)
+			break
+		}
+		read = append(read, chunk.(*wave.Int16Interleaved).Data...)
+	}
+	test.That(t, read, test.ShouldResemble, raw)
+}
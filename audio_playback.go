@@ -0,0 +1,162 @@
+package gostream
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/gen2brain/malgo"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// maxPlaybackBuffer is the most audio that will be buffered for playback before
+// older audio is dropped in favor of keeping latency low.
+const maxPlaybackBuffer = 200 * time.Millisecond
+
+type playbackAudioSink struct {
+	mu     sync.Mutex
+	logger golog.Logger
+	mCtx   *malgo.AllocatedContext
+	device *malgo.Device
+	info   wave.ChunkInfo
+	closed bool
+
+	// pendingMu is separate from mu so that the device can be torn down
+	// while holding mu without deadlocking with the data callback.
+	pendingMu       sync.Mutex
+	pending         []float32
+	pendingChannels int
+}
+
+// NewPlaybackAudioSink returns a sink that plays all audio written to it on the default
+// playback device of the system. The device is configured based on the first chunk of
+// audio written and is reconfigured if the sample rate or channel count changes.
+func NewPlaybackAudioSink(logger golog.Logger) (AudioSink, error) {
+	mCtx, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
+		logger.Debugw("malgo", "msg", message)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &playbackAudioSink{
+		logger: logger,
+		mCtx:   mCtx,
+	}, nil
+}
+
+// Write queues the audio chunk for playback.
+func (ps *playbackAudioSink) Write(_ context.Context, chunk wave.Audio) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return errSinkClosed
+	}
+
+	info := chunk.ChunkInfo()
+	if ps.device == nil || info.SamplingRate != ps.info.SamplingRate || info.Channels != ps.info.Channels {
+		if err := ps.initDevice(info); err != nil {
+			return err
+		}
+	}
+
+	ps.pendingMu.Lock()
+	defer ps.pendingMu.Unlock()
+	if asFloat, ok := chunk.(*wave.Float32Interleaved); ok {
+		ps.pending = append(ps.pending, asFloat.Data[:info.Len*info.Channels]...)
+	} else {
+		for i := 0; i < info.Len; i++ {
+			for ch := 0; ch < info.Channels; ch++ {
+				ps.pending = append(ps.pending,
+					float32(wave.Float32SampleFormat.Convert(chunk.At(i, ch)).(wave.Float32Sample)))
+			}
+		}
+	}
+
+	maxPending := samplesForDuration(info.SamplingRate, maxPlaybackBuffer) * info.Channels
+	if len(ps.pending) > maxPending {
+		if Debug {
+			ps.logger.Debugw("dropping audio to keep up with playback", "samples", len(ps.pending)-maxPending)
+		}
+		ps.pending = append(ps.pending[:0], ps.pending[len(ps.pending)-maxPending:]...)
+	}
+	return nil
+}
+
+// initDevice (re)initializes the playback device for audio of the given shape. It
+// assumes mu is held.
+func (ps *playbackAudioSink) initDevice(info wave.ChunkInfo) error {
+	if ps.device != nil {
+		ps.device.Uninit()
+		ps.device = nil
+	}
+	ps.info = info
+	ps.pendingMu.Lock()
+	ps.pending = ps.pending[:0]
+	ps.pendingChannels = info.Channels
+	ps.pendingMu.Unlock()
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.Playback.Format = malgo.FormatF32
+	deviceConfig.Playback.Channels = uint32(info.Channels)
+	deviceConfig.SampleRate = uint32(info.SamplingRate)
+	if info.Len > 0 {
+		deviceConfig.PeriodSizeInFrames = uint32(info.Len)
+	}
+
+	device, err := malgo.InitDevice(ps.mCtx.Context, deviceConfig, malgo.DeviceCallbacks{
+		Data: ps.onSendFrames,
+	})
+	if err != nil {
+		return err
+	}
+	if err := device.Start(); err != nil {
+		device.Uninit()
+		return err
+	}
+	ps.device = device
+	return nil
+}
+
+// onSendFrames is called by the device when it needs more audio. If not enough audio
+// is available, silence is played.
+func (ps *playbackAudioSink) onSendFrames(pOutput, _ []byte, frameCount uint32) {
+	ps.pendingMu.Lock()
+	defer ps.pendingMu.Unlock()
+
+	numSamples := int(frameCount) * ps.pendingChannels
+	available := numSamples
+	if available > len(ps.pending) {
+		available = len(ps.pending)
+	}
+	for i := 0; i < numSamples && (i+1)*4 <= len(pOutput); i++ {
+		var sample float32
+		if i < available {
+			sample = ps.pending[i]
+		}
+		binary.NativeEndian.PutUint32(pOutput[i*4:], math.Float32bits(sample))
+	}
+	ps.pending = append(ps.pending[:0], ps.pending[available:]...)
+}
+
+// Close stops playback and releases the playback device.
+func (ps *playbackAudioSink) Close(_ context.Context) error {
+	ps.mu.Lock()
+	if ps.closed {
+		ps.mu.Unlock()
+		return nil
+	}
+	ps.closed = true
+	device := ps.device
+	ps.device = nil
+	ps.mu.Unlock()
+
+	if device != nil {
+		device.Uninit()
+	}
+	err := ps.mCtx.Uninit()
+	ps.mCtx.Free()
+	return err
+}
@@ -0,0 +1,72 @@
+package gostream
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/utils"
+)
+
+// An AudioSink is anything that can consume audio chunks, such as a file or
+// a speaker.
+type AudioSink interface {
+	// Write consumes the given audio chunk. The chunk must not be retained
+	// after Write returns.
+	Write(ctx context.Context, chunk wave.Audio) error
+
+	// Close flushes any buffered audio and releases associated resources.
+	Close(ctx context.Context) error
+}
+
+var errSinkClosed = errors.New("sink closed")
+
+// StreamAudioSourceToSink streams the given audio source to the sink until the
+// context signals cancellation, the source is exhausted, or an error occurs. The
+// sink is not closed.
+func StreamAudioSourceToSink(ctx context.Context, as AudioSource, sink AudioSink) error {
+	stream, err := as.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.UncheckedError(stream.Close(ctx))
+	}()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk, release, err := stream.Next(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		err = sink.Write(ctx, chunk)
+		if release != nil {
+			release()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type nullAudioSink struct{}
+
+// NewNullAudioSink returns a sink that discards all audio written to it.
+func NewNullAudioSink() AudioSink {
+	return nullAudioSink{}
+}
+
+// Write discards the audio chunk.
+func (nullAudioSink) Write(_ context.Context, _ wave.Audio) error {
+	return nil
+}
+
+// Close does nothing.
+func (nullAudioSink) Close(_ context.Context) error {
+	return nil
+}
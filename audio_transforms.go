@@ -0,0 +1,310 @@
+package gostream
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+// The set of frame durations that audio can be rechunked to. These line up with
+// the frame sizes that Opus accepts and that are commonly used for RTP audio.
+const (
+	AudioFrameDuration10ms = 10 * time.Millisecond
+	AudioFrameDuration20ms = 20 * time.Millisecond
+	AudioFrameDuration40ms = 40 * time.Millisecond
+	AudioFrameDuration60ms = 60 * time.Millisecond
+)
+
+// validAudioFrameDuration returns whether or not the given duration can be used
+// to rechunk audio.
+func validAudioFrameDuration(frameDuration time.Duration) bool {
+	switch frameDuration {
+	case AudioFrameDuration10ms, AudioFrameDuration20ms, AudioFrameDuration40ms, AudioFrameDuration60ms:
+		return true
+	default:
+		return false
+	}
+}
+
+type rechunkAudioSource struct {
+	src    AudioSource
+	stream AudioStream
+
+	mu        sync.Mutex
+	rechunker audioRechunker
+	// err is the error that ended the underlying stream, returned once the buffered audio
+	// has been emitted.
+	err error
+}
+
+// An audioRechunker buffers arbitrarily sized audio chunks and splits them into chunks that
+// are frameDuration long.
+type audioRechunker struct {
+	frameDuration time.Duration
+	buffered      wave.Audio
+	// remainder is the fraction of a sample, in units of 1/time.Second of a sample, that the
+	// chunks emitted so far fell short of their duration. It is carried into the next chunk
+	// so that sampling rates that do not divide evenly into frameDuration do not drift.
+	remainder int64
+}
+
+// NewRechunkAudioSource returns a source that buffers arbitrarily sized audio chunks from
+// the given source and emits chunks that are frameDuration long. When a chunk would need a
+// fraction of a sample, such as 220.5 samples for 10ms at 22050Hz, chunks alternate between
+// the nearest whole sample counts so that they average out to frameDuration. At the end of
+// the source, the audio still buffered is emitted as a final chunk padded with silence. The
+// properties of the returned source reflect the new latency.
+func NewRechunkAudioSource(src AudioSource, frameDuration time.Duration) (AudioSource, error) {
+	if !validAudioFrameDuration(frameDuration) {
+		return nil, errors.Errorf("unsupported audio frame duration %s", frameDuration)
+	}
+
+	var props prop.Audio
+	if provider, ok := src.(AudioPropertyProvider); ok {
+		var err error
+		props, err = provider.MediaProperties(context.Background())
+		if err != nil {
+			return nil, err
+		}
+	}
+	props.Latency = frameDuration
+
+	ras := &rechunkAudioSource{
+		src:       src,
+		stream:    NewEmbeddedAudioStream(src),
+		rechunker: audioRechunker{frameDuration: frameDuration},
+	}
+	return NewAudioSource(ras, props), nil
+}
+
+// Read returns the next chunk of audio that is frameDuration long.
+func (ras *rechunkAudioSource) Read(ctx context.Context) (wave.Audio, func(), error) {
+	ras.mu.Lock()
+	defer ras.mu.Unlock()
+
+	for {
+		if frame, ok := ras.rechunker.next(); ok {
+			return frame, func() {}, nil
+		}
+		if ras.err != nil {
+			if frame, ok := ras.rechunker.flush(); ok {
+				return frame, func() {}, nil
+			}
+			return nil, nil, ras.err
+		}
+
+		chunk, release, err := ras.stream.Next(ctx)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, nil, err
+			}
+			ras.err = err
+			continue
+		}
+		ras.rechunker.bufferChunk(chunk)
+		if release != nil {
+			release()
+		}
+	}
+}
+
+// next removes and returns the next chunk that is frameDuration long, if enough audio is
+// buffered.
+func (r *audioRechunker) next() (wave.Audio, bool) {
+	if r.buffered == nil {
+		return nil, false
+	}
+	info := r.buffered.ChunkInfo()
+	frameSamples, remainder := r.frameSamples(info.SamplingRate)
+	if frameSamples == 0 || info.Len < frameSamples {
+		return nil, false
+	}
+	r.remainder = remainder
+	return r.nextFrame(frameSamples), true
+}
+
+// flush returns the audio still buffered as a chunk that is padded with silence to be
+// frameDuration long, if any audio is buffered.
+func (r *audioRechunker) flush() (wave.Audio, bool) {
+	if r.buffered == nil || r.buffered.ChunkInfo().Len == 0 {
+		return nil, false
+	}
+	frameSamples, _ := r.frameSamples(r.buffered.ChunkInfo().SamplingRate)
+	if frameSamples == 0 {
+		return nil, false
+	}
+	r.remainder = 0
+	r.padBuffered(frameSamples)
+	return r.nextFrame(frameSamples), true
+}
+
+// bufferChunk copies the given chunk onto the end of the buffered audio. If the
+// sample format changes, the buffered audio is converted to the new format. If the
+// shape of the audio changes, anything previously buffered is discarded since it
+// can no longer be joined with the new audio.
+func (r *audioRechunker) bufferChunk(chunk wave.Audio) {
+	if chunk == nil {
+		return
+	}
+	info := chunk.ChunkInfo()
+	if r.buffered != nil {
+		bufInfo := r.buffered.ChunkInfo()
+		if bufInfo.SamplingRate != info.SamplingRate || bufInfo.Channels != info.Channels {
+			r.buffered = nil
+			r.remainder = 0
+		}
+	}
+
+	switch c := chunk.(type) {
+	case *wave.Int16Interleaved:
+		buf := r.int16Buffer(info)
+		buf.Data = append(buf.Data, c.Data[:c.Size.Len*c.Size.Channels]...)
+		buf.Size.Len += c.Size.Len
+	case *wave.Float32Interleaved:
+		buf := r.float32Buffer(info)
+		buf.Data = append(buf.Data, c.Data[:c.Size.Len*c.Size.Channels]...)
+		buf.Size.Len += c.Size.Len
+	default:
+		switch chunk.SampleFormat() {
+		case wave.Int16SampleFormat:
+			buf := r.int16Buffer(info)
+			for i := 0; i < info.Len; i++ {
+				for ch := 0; ch < info.Channels; ch++ {
+					buf.Data = append(buf.Data, int16(wave.Int16SampleFormat.Convert(chunk.At(i, ch)).(wave.Int16Sample)))
+				}
+			}
+			buf.Size.Len += info.Len
+		default:
+			buf := r.float32Buffer(info)
+			for i := 0; i < info.Len; i++ {
+				for ch := 0; ch < info.Channels; ch++ {
+					buf.Data = append(buf.Data, float32(wave.Float32SampleFormat.Convert(chunk.At(i, ch)).(wave.Float32Sample)))
+				}
+			}
+			buf.Size.Len += info.Len
+		}
+	}
+}
+
+// int16Buffer returns the buffered audio as int16 samples. Float32 samples already
+// buffered are converted so that none are lost when the source changes its format.
+func (r *audioRechunker) int16Buffer(info wave.ChunkInfo) *wave.Int16Interleaved {
+	switch buffered := r.buffered.(type) {
+	case *wave.Int16Interleaved:
+		return buffered
+	case *wave.Float32Interleaved:
+		buf := r.resetInt16(info)
+		for _, sample := range buffered.Data {
+			buf.Data = append(buf.Data, int16(wave.Int16SampleFormat.Convert(wave.Float32Sample(sample)).(wave.Int16Sample)))
+		}
+		buf.Size.Len = buffered.Size.Len
+		return buf
+	default:
+		return r.resetInt16(info)
+	}
+}
+
+// float32Buffer returns the buffered audio as float32 samples. Int16 samples already
+// buffered are converted so that none are lost when the source changes its format.
+func (r *audioRechunker) float32Buffer(info wave.ChunkInfo) *wave.Float32Interleaved {
+	switch buffered := r.buffered.(type) {
+	case *wave.Float32Interleaved:
+		return buffered
+	case *wave.Int16Interleaved:
+		buf := r.resetFloat32(info)
+		for _, sample := range buffered.Data {
+			buf.Data = append(buf.Data, float32(wave.Float32SampleFormat.Convert(wave.Int16Sample(sample)).(wave.Float32Sample)))
+		}
+		buf.Size.Len = buffered.Size.Len
+		return buf
+	default:
+		return r.resetFloat32(info)
+	}
+}
+
+func (r *audioRechunker) resetInt16(info wave.ChunkInfo) *wave.Int16Interleaved {
+	buf := &wave.Int16Interleaved{
+		Size: wave.ChunkInfo{SamplingRate: info.SamplingRate, Channels: info.Channels},
+	}
+	r.buffered = buf
+	return buf
+}
+
+func (r *audioRechunker) resetFloat32(info wave.ChunkInfo) *wave.Float32Interleaved {
+	buf := &wave.Float32Interleaved{
+		Size: wave.ChunkInfo{SamplingRate: info.SamplingRate, Channels: info.Channels},
+	}
+	r.buffered = buf
+	return buf
+}
+
+// frameSamples returns how many samples the next chunk at the given sampling rate holds
+// and the remainder to carry into the chunk after it.
+func (r *audioRechunker) frameSamples(samplingRate int) (int, int64) {
+	total := int64(samplingRate)*int64(r.frameDuration) + r.remainder
+	return int(total / int64(time.Second)), total % int64(time.Second)
+}
+
+// padBuffered appends silence to the buffered audio until it holds frameSamples samples.
+func (r *audioRechunker) padBuffered(frameSamples int) {
+	switch buf := r.buffered.(type) {
+	case *wave.Int16Interleaved:
+		for buf.Size.Len < frameSamples {
+			buf.Data = append(buf.Data, make([]int16, buf.Size.Channels)...)
+			buf.Size.Len++
+		}
+	case *wave.Float32Interleaved:
+		for buf.Size.Len < frameSamples {
+			buf.Data = append(buf.Data, make([]float32, buf.Size.Channels)...)
+			buf.Size.Len++
+		}
+	}
+}
+
+// nextFrame removes frameSamples samples from the front of the buffered audio and
+// returns them as a new chunk.
+func (r *audioRechunker) nextFrame(frameSamples int) wave.Audio {
+	switch buf := r.buffered.(type) {
+	case *wave.Int16Interleaved:
+		n := frameSamples * buf.Size.Channels
+		frame := wave.NewInt16Interleaved(wave.ChunkInfo{
+			Len:          frameSamples,
+			Channels:     buf.Size.Channels,
+			SamplingRate: buf.Size.SamplingRate,
+		})
+		copy(frame.Data, buf.Data[:n])
+		buf.Data = append(buf.Data[:0], buf.Data[n:]...)
+		buf.Size.Len -= frameSamples
+		return frame
+	case *wave.Float32Interleaved:
+		n := frameSamples * buf.Size.Channels
+		frame := wave.NewFloat32Interleaved(wave.ChunkInfo{
+			Len:          frameSamples,
+			Channels:     buf.Size.Channels,
+			SamplingRate: buf.Size.SamplingRate,
+		})
+		copy(frame.Data, buf.Data[:n])
+		buf.Data = append(buf.Data[:0], buf.Data[n:]...)
+		buf.Size.Len -= frameSamples
+		return frame
+	default:
+		return nil
+	}
+}
+
+// Close closes the underlying source.
+func (ras *rechunkAudioSource) Close(ctx context.Context) error {
+	return multierr.Combine(ras.stream.Close(ctx), ras.src.Close(ctx))
+}
+
+// samplesForDuration returns how many samples at the given sampling rate
+// make up the given duration.
+func samplesForDuration(samplingRate int, dur time.Duration) int {
+	return int(time.Duration(samplingRate) * dur / time.Second)
+}
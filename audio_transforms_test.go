@@ -0,0 +1,176 @@
+package gostream_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+
+	"github.com/edaniels/gostream"
+)
+
+// countingAudioReader produces odd sized chunks whose samples count up from zero. It ends
+// after total samples, if set, and produces float32 chunks from float32From samples on, if set.
+type countingAudioReader struct {
+	chunkLen     int
+	samplingRate int
+	total        int
+	float32From  int
+	next         int16
+}
+
+func (r *countingAudioReader) Read(_ context.Context) (wave.Audio, func(), error) {
+	chunkLen := r.chunkLen
+	if r.total != 0 {
+		if int(r.next) >= r.total {
+			return nil, nil, io.EOF
+		}
+		if remaining := r.total - int(r.next); remaining < chunkLen {
+			chunkLen = remaining
+		}
+	}
+	samplingRate := r.samplingRate
+	if samplingRate == 0 {
+		samplingRate = 48000
+	}
+	info := wave.ChunkInfo{Len: chunkLen, Channels: 2, SamplingRate: samplingRate}
+	var chunk wave.EditableAudio = wave.NewInt16Interleaved(info)
+	if r.float32From != 0 && int(r.next) >= r.float32From {
+		chunk = wave.NewFloat32Interleaved(info)
+	}
+	for i := 0; i < chunkLen; i++ {
+		chunk.Set(i, 0, wave.Int16Sample(r.next))
+		chunk.Set(i, 1, wave.Int16Sample(-r.next))
+		r.next++
+	}
+	return chunk, func() {}, nil
+}
+
+func (r *countingAudioReader) Close(_ context.Context) error {
+	return nil
+}
+
+func TestRechunkAudioSource(t *testing.T) {
+	_, err := gostream.NewRechunkAudioSource(
+		gostream.NewAudioSource(&countingAudioReader{chunkLen: 1}, prop.Audio{}), 15*time.Millisecond)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	// 7ms of audio at 48kHz
+	src := gostream.NewAudioSource(&countingAudioReader{chunkLen: 336}, prop.Audio{
+		Latency:      7 * time.Millisecond,
+		SampleRate:   48000,
+		ChannelCount: 2,
+	})
+	rechunked, err := gostream.NewRechunkAudioSource(src, gostream.AudioFrameDuration20ms)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, rechunked.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	props, err := rechunked.(gostream.AudioPropertyProvider).MediaProperties(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.Latency, test.ShouldEqual, gostream.AudioFrameDuration20ms)
+	test.That(t, props.SampleRate, test.ShouldEqual, 48000)
+
+	stream, err := rechunked.Stream(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, stream.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	var expected int16
+	for i := 0; i < 5; i++ {
+		chunk, release, err := stream.Next(context.Background())
+		test.That(t, err, test.ShouldBeNil)
+		info := chunk.ChunkInfo()
+		test.That(t, info.Len, test.ShouldEqual, 960)
+		test.That(t, info.Channels, test.ShouldEqual, 2)
+		test.That(t, info.SamplingRate, test.ShouldEqual, 48000)
+		asInt16, ok := chunk.(*wave.Int16Interleaved)
+		test.That(t, ok, test.ShouldBeTrue)
+		for j := 0; j < info.Len; j++ {
+			test.That(t, asInt16.At(j, 0), test.ShouldEqual, wave.Int16Sample(expected))
+			test.That(t, asInt16.At(j, 1), test.ShouldEqual, wave.Int16Sample(-expected))
+			expected++
+		}
+		release()
+	}
+}
+
+func TestRechunkAudioSourceEnd(t *testing.T) {
+	// 10ms at 22050Hz is 220.5 samples.
+	src := gostream.NewAudioSource(&countingAudioReader{chunkLen: 100, samplingRate: 22050, total: 1000}, prop.Audio{})
+	rechunked, err := gostream.NewRechunkAudioSource(src, gostream.AudioFrameDuration10ms)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, rechunked.Close(context.Background()), test.ShouldBeNil)
+	}()
+	stream, err := rechunked.Stream(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, stream.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	var lens []int
+	var read []int16
+	for {
+		chunk, release, err := stream.Next(context.Background())
+		if err != nil {
+			test.That(t, err, test.ShouldBeError, io.EOF)
+			break
+		}
+		lens = append(lens, chunk.ChunkInfo().Len)
+		for i := 0; i < chunk.ChunkInfo().Len; i++ {
+			read = append(read, int16(chunk.At(i, 0).(wave.Int16Sample)))
+		}
+		release()
+	}
+
+	// the chunks alternate to average out to 10ms and the last one is padded with silence.
+	test.That(t, lens, test.ShouldResemble, []int{220, 221, 220, 221, 220})
+	for i, sample := range read {
+		if i < 1000 {
+			test.That(t, sample, test.ShouldEqual, int16(i))
+		} else {
+			test.That(t, sample, test.ShouldEqual, 0)
+		}
+	}
+}
+
+func TestRechunkAudioSourceFormatChange(t *testing.T) {
+	// 48 samples are still buffered when the source switches to float32 after its third chunk.
+	src := gostream.NewAudioSource(&countingAudioReader{chunkLen: 336, float32From: 3 * 336}, prop.Audio{})
+	rechunked, err := gostream.NewRechunkAudioSource(src, gostream.AudioFrameDuration20ms)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, rechunked.Close(context.Background()), test.ShouldBeNil)
+	}()
+	stream, err := rechunked.Stream(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, stream.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	var expected int16
+	for i := 0; i < 3; i++ {
+		chunk, release, err := stream.Next(context.Background())
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, chunk.ChunkInfo().Len, test.ShouldEqual, 960)
+		if i == 0 {
+			test.That(t, chunk.SampleFormat(), test.ShouldEqual, wave.Int16SampleFormat)
+		} else {
+			test.That(t, chunk.SampleFormat(), test.ShouldEqual, wave.Float32SampleFormat)
+		}
+		// no samples are lost across the switch.
+		for j := 0; j < chunk.ChunkInfo().Len; j++ {
+			test.That(t, wave.Int16SampleFormat.Convert(chunk.At(j, 0)), test.ShouldEqual, wave.Int16Sample(expected))
+			test.That(t, wave.Int16SampleFormat.Convert(chunk.At(j, 1)), test.ShouldEqual, wave.Int16Sample(-expected))
+			expected++
+		}
+		release()
+	}
+}
@@ -2,24 +2,17 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"fmt"
-	"io"
 	"sync"
-	"unsafe"
 
 	"github.com/edaniels/golog"
 	utils "github.com/edaniels/goutils"
-	"github.com/gen2brain/malgo"
 	// register microphone drivers.
 	_ "github.com/pion/mediadevices/pkg/driver/microphone"
 	"github.com/pion/webrtc/v3"
 	"github.com/pkg/errors"
 	"go.uber.org/multierr"
 	vutils "go.viam.com/utils"
-	hopus "gopkg.in/hraban/opus.v2"
 
 	"github.com/edaniels/gostream"
 	"github.com/edaniels/gostream/codec/opus"
@@ -107,7 +100,9 @@ func runServer(
 				peerCancelationMu.Unlock()
 				activePlayers.Add(1)
 				defer activePlayers.Done()
-				decodeAndPlayTrack(cancelCtx, track)
+				if err := playTrack(cancelCtx, track, logger); err != nil {
+					logger.Errorw("error playing track", "error", err)
+				}
 			})
 		}))
 		serverOpts = append(serverOpts, gostream.WithStandaloneOnPeerRemoved(func(pc *webrtc.PeerConnection) {
@@ -132,149 +127,27 @@ func runServer(
 	return gostream.StreamAudioSource(ctx, audioSource, stream)
 }
 
-func decodeAndPlayTrack(ctx context.Context, track *webrtc.TrackRemote) {
-	var hostEndian binary.ByteOrder
-
-	//nolint:gosec
-	switch v := *(*uint16)(unsafe.Pointer(&([]byte{0x12, 0x34}[0]))); v {
-	case 0x1234:
-		hostEndian = binary.BigEndian
-	case 0x3412:
-		hostEndian = binary.LittleEndian
-	default:
-		panic(fmt.Sprintf("failed to determine host endianness: %x", v))
+// playTrack plays the audio received on the given track until the context is
+// canceled or the track ends.
+func playTrack(ctx context.Context, track *webrtc.TrackRemote, logger golog.Logger) (err error) {
+	source, err := opus.NewTrackAudioSource(track)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		err = multierr.Combine(err, source.Close(ctx))
+	}()
 
-	switch track.Kind() {
-	case webrtc.RTPCodecTypeAudio:
-		{
-			mCtx, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
-				logger.Debugw("malgo", "msg", message)
-			})
-			if err != nil {
-				panic(err)
-			}
-			defer func() {
-				vutils.UncheckedErrorFunc(mCtx.Uninit)
-				mCtx.Free()
-			}()
-
-			codec := track.Codec()
-			channels := codec.Channels
-			sampleRate := codec.ClockRate
-
-			dec, err := hopus.NewDecoder(int(sampleRate), int(channels))
-			if err != nil {
-				panic(err)
-			}
-
-			const maxOpusFrameSizeMs = 60
-			maxFrameSize := float32(channels) * maxOpusFrameSizeMs * float32(sampleRate) / 1000
-			dataPool := sync.Pool{
-				New: func() interface{} {
-					newData := make([]float32, int(maxFrameSize))
-					return &newData
-				},
-			}
-			decodeRTPData := func() (*[]float32, int, error) {
-				data, _, err := track.ReadRTP()
-				if err != nil {
-					return nil, 0, err
-				}
-				if len(data.Payload) == 0 {
-					return nil, 0, nil
-				}
-
-				pcmData := dataPool.Get().(*[]float32)
-				n, err := dec.DecodeFloat32(data.Payload, *pcmData)
-				pcmActual := (*pcmData)[:n*int(channels)]
-				return &pcmActual, n, err
-			}
-
-			var periodSizeInFrames int
-			for {
-				// we assume all packets will contain this amount of samples going forward
-				// if it's anything larger than one RTP packet (or close to MTU?) then
-				// this could fail.
-				_, numSamples, err := decodeRTPData()
-				if err != nil {
-					panic(err)
-				}
-				if numSamples > 0 {
-					periodSizeInFrames = numSamples
-					break
-				}
-			}
-
-			deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
-			deviceConfig.Playback.Format = malgo.FormatF32 // tied to what we opus decode to
-			deviceConfig.Playback.Channels = uint32(channels)
-			deviceConfig.SampleRate = sampleRate
-			deviceConfig.PeriodSizeInFrames = uint32(periodSizeInFrames)
-			sizeInBytes := malgo.SampleSizeInBytes(deviceConfig.Playback.Format)
-
-			pcmChan := make(chan *[]float32)
-
-			onSendFrames := func(pOutput, _ []byte, frameCount uint32) {
-				if ctx.Err() != nil {
-					return
-				}
-				samplesRequested := frameCount * deviceConfig.Playback.Channels * uint32(sizeInBytes)
-				select {
-				case <-ctx.Done():
-					return
-				case pcm := <-pcmChan:
-					pcmToWrite := *pcm
-					if len(pcmToWrite) > int(samplesRequested) {
-						logger.Errorw("not enough samples requested; trimming our own data", "samples_requested", samplesRequested)
-						pcmToWrite = pcmToWrite[:samplesRequested]
-					}
-					pOutput = pOutput[:0]
-					buf := bytes.NewBuffer(pOutput)
-					if err := binary.Write(buf, hostEndian, pcmToWrite); err != nil {
-						logger.Errorw("error writing to pcm buf", "error", err)
-					}
-					dataPool.Put(pcm)
-				}
-			}
-
-			playbackCallbacks := malgo.DeviceCallbacks{
-				Data: onSendFrames,
-			}
-
-			device, err := malgo.InitDevice(mCtx.Context, deviceConfig, playbackCallbacks)
-			if err != nil {
-				panic(err)
-			}
-
-			err = device.Start()
-			if err != nil {
-				panic(err)
-			}
-
-			defer device.Uninit()
+	sink, err := gostream.NewPlaybackAudioSink(logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Combine(err, sink.Close(ctx))
+	}()
 
-			for {
-				if ctx.Err() != nil {
-					return
-				}
-				pcmData, numSamples, err := decodeRTPData()
-				if errors.Is(err, io.EOF) {
-					return
-				}
-				if numSamples == 0 {
-					continue
-				}
-				select {
-				case <-ctx.Done():
-					return
-				case pcmChan <- pcmData:
-				}
-			}
-		}
-	case webrtc.RTPCodecTypeVideo:
-		fallthrough
-	default:
-		panic(errors.Errorf("Unsupported track kind %v", track.Kind()))
+	if err := gostream.StreamAudioSourceToSink(ctx, source, sink); err != nil && !errors.Is(err, context.Canceled) {
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,23 @@
+package codec
+
+import (
+	"context"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// An AudioDecoder is anything that can decode bytes produced by an AudioEncoder of the same
+// type (see AudioDecoderFactory.MIMEType) back into audio chunks.
+type AudioDecoder interface {
+	// Decode decodes a single encoded frame. A nil chunk and nil error are returned if
+	// the data decoded to no audio.
+	Decode(ctx context.Context, data []byte) (wave.Audio, error)
+	Close()
+}
+
+// An AudioDecoderFactory produces AudioDecoders and provides information about the underlying decoder itself.
+type AudioDecoderFactory interface {
+	New(sampleRate, channelCount int, logger golog.Logger) (AudioDecoder, error)
+	MIMEType() string
+}
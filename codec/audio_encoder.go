@@ -18,6 +18,42 @@ type AudioEncoder interface {
 
 // An AudioEncoderFactory produces AudioEncoders and provides information about the underlying encoder itself.
 type AudioEncoderFactory interface {
-	New(sampleRate, channelCount int, latency time.Duration, logger golog.Logger) (AudioEncoder, error)
+	New(sampleRate, channelCount int, latency time.Duration, opts AudioEncoderOptions, logger golog.Logger) (AudioEncoder, error)
 	MIMEType() string
 }
+
+// An AudioApplication tells an encoder what kind of audio it encodes so that it can tune
+// itself accordingly.
+type AudioApplication string
+
+// The set of audio applications an encoder may support.
+const (
+	AudioApplicationDefault  AudioApplication = ""
+	AudioApplicationVoIP     AudioApplication = "voip"
+	AudioApplicationAudio    AudioApplication = "audio"
+	AudioApplicationLowDelay AudioApplication = "lowdelay"
+)
+
+// AudioEncoderOptions configures how an AudioEncoder encodes. The zero value of any
+// field leaves the encoder's default in place. Encoders fail to be created with options
+// they cannot honor; every codec package documents the options it supports.
+type AudioEncoderOptions struct {
+	// TargetBitrate is the bitrate, in bits per second, the encoder aims for.
+	TargetBitrate int
+
+	// Complexity trades CPU usage for quality. For Opus this ranges from 1 to 10.
+	Complexity int
+
+	// FEC enables in-band forward error correction.
+	FEC bool
+
+	// ExpectedPacketLossPercent is the packet loss, from 0 to 100, the encoder should expect.
+	// With FEC, it determines how much redundancy is added.
+	ExpectedPacketLossPercent int
+
+	// DTX enables discontinuous transmission which sends less data during silence.
+	DTX bool
+
+	// Application is the kind of audio being encoded (e.g. speech or music).
+	Application AudioApplication
+}
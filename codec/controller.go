@@ -0,0 +1,16 @@
+package codec
+
+// A BitrateController is an encoder whose target bitrate can be changed while it is
+// running. VideoEncoders and AudioEncoders may optionally implement it.
+type BitrateController interface {
+	// SetBitrate changes the target bitrate, in bits per second, for all
+	// subsequently encoded data.
+	SetBitrate(bitrate int) error
+}
+
+// A KeyFrameController is a VideoEncoder that can be asked to produce a key frame on
+// demand, such as when a viewer joins or loses packets.
+type KeyFrameController interface {
+	// ForceKeyFrame forces the next encoded frame to be a key frame.
+	ForceKeyFrame() error
+}
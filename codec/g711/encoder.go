@@ -0,0 +1,168 @@
+// Package g711 contains the G.711 (PCMU and PCMA) audio codec.
+package g711
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pkg/errors"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+	"github.com/edaniels/gostream/codec/internal/pcm"
+)
+
+// Law determines the companding law of a G.711 codec.
+type Law string
+
+// The set of allowed G.711 laws.
+const (
+	LawMu Law = "pcmu"
+	LawA  Law = "pcma"
+)
+
+// SampleRate is the only sample rate G.711 supports. Audio of other rates is resampled.
+const SampleRate = 8000
+
+// bitrate is the fixed bitrate of G.711.
+const bitrate = 64000
+
+var errEncoderClosed = errors.New("encoder closed")
+
+type encoder struct {
+	mu        sync.Mutex
+	buf       *pcm.Buffer
+	frameSize int
+	compand   func(int16) byte
+	closed    bool
+}
+
+// NewEncoder returns a G.711 encoder of the given law that encodes audio into frames of the
+// given latency. Audio is downmixed to mono and resampled to 8kHz. No encoder option is supported
+// other than a TargetBitrate of 64 kbit/s, which G.711 always has.
+func NewEncoder(
+	law Law,
+	latency time.Duration,
+	opts ourcodec.AudioEncoderOptions,
+	_ golog.Logger,
+) (ourcodec.AudioEncoder, error) {
+	var compand func(int16) byte
+	switch law {
+	case LawMu:
+		compand = linearToULaw
+	case LawA:
+		compand = linearToALaw
+	default:
+		return nil, errors.Errorf("unsupported g711 law: %s", law)
+	}
+	frameSize := int(time.Duration(SampleRate) * latency / time.Second)
+	if frameSize <= 0 {
+		return nil, errors.Errorf("unsupported g711 latency %v", latency)
+	}
+	if (opts.TargetBitrate != 0 && opts.TargetBitrate != bitrate) || opts.Complexity != 0 || opts.FEC || opts.DTX ||
+		opts.ExpectedPacketLossPercent != 0 || opts.Application != ourcodec.AudioApplicationDefault {
+		return nil, errors.Errorf(
+			"unsupported g711 encoder options: target bitrate %d, complexity %d, fec %t, dtx %t, expected packet loss %d%%, application %q",
+			opts.TargetBitrate, opts.Complexity, opts.FEC, opts.DTX, opts.ExpectedPacketLossPercent, opts.Application)
+	}
+	return &encoder{
+		buf:       pcm.NewBuffer(SampleRate),
+		frameSize: frameSize,
+		compand:   compand,
+	}, nil
+}
+
+// Encode buffers the given audio chunk and encodes every complete frame available. Since
+// G.711 frames are just consecutive samples, a chunk longer than a frame is encoded into
+// one longer frame rather than being left to build up in the buffer. The returned bool
+// indicates whether or not a frame was produced.
+func (e *encoder) Encode(_ context.Context, chunk wave.Audio) ([]byte, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, false, errEncoderClosed
+	}
+	e.buf.Write(chunk)
+	var encoded []byte
+	for frame, ok := e.buf.Next(e.frameSize); ok; frame, ok = e.buf.Next(e.frameSize) {
+		for _, sample := range frame {
+			encoded = append(encoded, e.compand(sample))
+		}
+	}
+	if encoded == nil {
+		return nil, false, nil
+	}
+	return encoded, true, nil
+}
+
+// Close releases the encoder.
+func (e *encoder) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+}
+
+// uLawSegmentEnds are the upper bounds of each mu-law segment of a biased 14-bit magnitude.
+var uLawSegmentEnds = [8]int{0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF}
+
+// aLawSegmentEnds are the upper bounds of each A-law segment of a 13-bit magnitude.
+var aLawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// segmentOf returns the segment the given magnitude falls in or the number of segments
+// if it is out of range.
+func segmentOf(value int, ends *[8]int) int {
+	for i, end := range ends {
+		if value <= end {
+			return i
+		}
+	}
+	return len(ends)
+}
+
+// linearToULaw compands a 16-bit linear sample with the mu-law.
+func linearToULaw(sample int16) byte {
+	const (
+		bias = 0x84 >> 2
+		clip = 8159
+	)
+	value := int(sample) >> 2
+	mask := 0xFF
+	if value < 0 {
+		mask = 0x7F
+		value = -value
+	}
+	if value > clip {
+		value = clip
+	}
+	value += bias
+
+	segment := segmentOf(value, &uLawSegmentEnds)
+	if segment >= len(uLawSegmentEnds) {
+		return byte(0x7F ^ mask)
+	}
+	return byte((segment<<4 | (value>>(segment+1))&0x0F) ^ mask)
+}
+
+// linearToALaw compands a 16-bit linear sample with the A-law.
+func linearToALaw(sample int16) byte {
+	value := int(sample) >> 3
+	mask := 0xD5
+	if value < 0 {
+		mask = 0x55
+		value = -value - 1
+	}
+
+	segment := segmentOf(value, &aLawSegmentEnds)
+	if segment >= len(aLawSegmentEnds) {
+		return byte(0x7F ^ mask)
+	}
+	companded := segment << 4
+	if segment < 2 {
+		companded |= (value >> 1) & 0x0F
+	} else {
+		companded |= (value >> segment) & 0x0F
+	}
+	return byte(companded ^ mask)
+}
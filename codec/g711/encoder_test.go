@@ -0,0 +1,78 @@
+package g711
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+func TestCompanding(t *testing.T) {
+	test.That(t, linearToULaw(0), test.ShouldEqual, 0xFF)
+	test.That(t, linearToULaw(-4), test.ShouldEqual, 0x7E)
+	test.That(t, linearToULaw(math.MaxInt16), test.ShouldEqual, 0x80)
+	test.That(t, linearToULaw(math.MinInt16), test.ShouldEqual, 0x00)
+
+	test.That(t, linearToALaw(0), test.ShouldEqual, 0xD5)
+	test.That(t, linearToALaw(-8), test.ShouldEqual, 0x55)
+	test.That(t, linearToALaw(math.MaxInt16), test.ShouldEqual, 0xAA)
+	test.That(t, linearToALaw(math.MinInt16), test.ShouldEqual, 0x2A)
+}
+
+func TestEncodeResamples(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	_, err := NewEncoder(LawMu, 20*time.Millisecond, ourcodec.AudioEncoderOptions{FEC: true}, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	enc, err := NewEncoder(LawMu, 20*time.Millisecond, ourcodec.AudioEncoderOptions{TargetBitrate: 64000}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	// 10ms of 48kHz stereo silence at a time.
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 480, Channels: 2, SamplingRate: 48000})
+	var frames [][]byte
+	for i := 0; i < 10; i++ {
+		encoded, ready, err := enc.Encode(context.Background(), chunk)
+		test.That(t, err, test.ShouldBeNil)
+		if ready {
+			frames = append(frames, encoded)
+		}
+	}
+	test.That(t, len(frames), test.ShouldBeBetweenOrEqual, 4, 5)
+	for _, frame := range frames {
+		test.That(t, frame, test.ShouldHaveLength, 160)
+		for _, b := range frame {
+			test.That(t, b, test.ShouldEqual, 0xFF)
+		}
+	}
+
+	_, err = NewEncoder(LawA, 0, ourcodec.AudioEncoderOptions{}, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestEncodeLongChunks(t *testing.T) {
+	enc, err := NewEncoder(LawA, 20*time.Millisecond, ourcodec.AudioEncoderOptions{}, golog.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	// 50ms at a time is two and a half frames.
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 400, Channels: 1, SamplingRate: SampleRate})
+	var encodedLen int
+	for i := 0; i < 20; i++ {
+		encoded, ready, err := enc.Encode(context.Background(), chunk)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, ready, test.ShouldBeTrue)
+		test.That(t, len(encoded)%160, test.ShouldEqual, 0)
+		encodedLen += len(encoded)
+		// every complete frame is encoded so no more than a frame stays buffered.
+		test.That(t, enc.(*encoder).buf.Buffered(), test.ShouldBeLessThan, 160)
+	}
+	// all but the frame still being buffered is encoded.
+	test.That(t, encodedLen, test.ShouldBeGreaterThanOrEqualTo, 20*400-160)
+}
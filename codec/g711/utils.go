@@ -0,0 +1,51 @@
+package g711
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/edaniels/golog"
+
+	"github.com/edaniels/gostream"
+	"github.com/edaniels/gostream/codec"
+)
+
+// DefaultStreamConfig configures G.711 mu-law (PCMU) as the audio encoder for a stream.
+var DefaultStreamConfig gostream.StreamConfig
+
+// DefaultPCMAStreamConfig configures G.711 A-law (PCMA) as the audio encoder for a stream.
+var DefaultPCMAStreamConfig gostream.StreamConfig
+
+func init() {
+	DefaultStreamConfig.AudioEncoderFactory = NewEncoderFactory(LawMu)
+	DefaultPCMAStreamConfig.AudioEncoderFactory = NewEncoderFactory(LawA)
+}
+
+// NewEncoderFactory returns a G.711 audio encoder factory of the given law.
+func NewEncoderFactory(law Law) codec.AudioEncoderFactory {
+	return &factory{law}
+}
+
+type factory struct {
+	law Law
+}
+
+func (f *factory) New(
+	sampleRate, channelCount int,
+	latency time.Duration,
+	opts codec.AudioEncoderOptions,
+	logger golog.Logger,
+) (codec.AudioEncoder, error) {
+	return NewEncoder(f.law, latency, opts, logger)
+}
+
+func (f *factory) MIMEType() string {
+	switch f.law {
+	case LawMu:
+		return "audio/PCMU"
+	case LawA:
+		return "audio/PCMA"
+	default:
+		panic(fmt.Errorf("unknown law %q", f.law))
+	}
+}
@@ -0,0 +1,286 @@
+package g722
+
+// This is an implementation of the 64 kbit/s mode of the ITU-T G.722 encoder. The block
+// names in comments refer to the blocks of the ITU-T recommendation.
+
+var (
+	q6 = [32]int{
+		0, 35, 72, 110, 150, 190, 233, 276,
+		323, 370, 422, 473, 530, 587, 650, 714,
+		786, 858, 940, 1023, 1121, 1219, 1339, 1458,
+		1612, 1765, 1980, 2195, 2557, 2919, 0, 0,
+	}
+	iln = [32]int{
+		0, 63, 62, 31, 30, 29, 28, 27,
+		26, 25, 24, 23, 22, 21, 20, 19,
+		18, 17, 16, 15, 14, 13, 12, 11,
+		10, 9, 8, 7, 6, 5, 4, 0,
+	}
+	ilp = [32]int{
+		0, 61, 60, 59, 58, 57, 56, 55,
+		54, 53, 52, 51, 50, 49, 48, 47,
+		46, 45, 44, 43, 42, 41, 40, 39,
+		38, 37, 36, 35, 34, 33, 32, 0,
+	}
+	wl   = [8]int{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	rl42 = [16]int{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	ilb  = [32]int{
+		2048, 2093, 2139, 2186, 2233, 2282, 2332,
+		2383, 2435, 2489, 2543, 2599, 2656, 2714,
+		2774, 2834, 2896, 2960, 3025, 3091, 3158,
+		3228, 3298, 3371, 3444, 3520, 3597, 3676,
+		3756, 3838, 3922, 4008,
+	}
+	qm4 = [16]int{
+		0, -20456, -12896, -8968,
+		-6288, -4240, -2584, -1200,
+		20456, 12896, 8968, 6288,
+		4240, 2584, 1200, 0,
+	}
+	qm2       = [4]int{-7408, -1616, 7408, 1616}
+	qmfCoeffs = [12]int{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+	ihn       = [3]int{0, 1, 0}
+	ihp       = [3]int{0, 3, 2}
+	wh        = [3]int{0, -214, 798}
+	rh2       = [4]int{2, 1, 2, 1}
+)
+
+// band is the adaptive predictor state of either the lower or the higher sub-band.
+type band struct {
+	s, sp, sz int
+	r, a, ap  [3]int
+	p         [3]int
+	d, b, bp  [7]int
+	sg        [7]int
+	nb, det   int
+}
+
+// adpcmEncoder holds the state of a G.722 encoder across frames.
+type adpcmEncoder struct {
+	x    [24]int
+	low  band
+	high band
+}
+
+func newADPCMEncoder() *adpcmEncoder {
+	enc := &adpcmEncoder{}
+	enc.low.det = 32
+	enc.high.det = 8
+	return enc
+}
+
+func saturate(amp int) int {
+	if amp > 32767 {
+		return 32767
+	}
+	if amp < -32768 {
+		return -32768
+	}
+	return amp
+}
+
+// encode encodes pairs of 16kHz samples into one byte each. samples must have an
+// even length.
+func (e *adpcmEncoder) encode(samples []int16, out []byte) {
+	for j := 0; j+1 < len(samples); j += 2 {
+		// Apply the transmit QMF to split the signal into two sub-bands.
+		copy(e.x[:22], e.x[2:])
+		e.x[22] = int(samples[j])
+		e.x[23] = int(samples[j+1])
+		var sumEven, sumOdd int
+		for i := 0; i < 12; i++ {
+			sumOdd += e.x[2*i] * qmfCoeffs[i]
+			sumEven += e.x[2*i+1] * qmfCoeffs[11-i]
+		}
+		xLow := (sumEven + sumOdd) >> 14
+		xHigh := (sumEven - sumOdd) >> 14
+
+		lowCode := e.encodeLow(xLow)
+		highCode := e.encodeHigh(xHigh)
+		out[j/2] = byte(highCode<<6 | lowCode)
+	}
+}
+
+func (e *adpcmEncoder) encodeLow(xLow int) int {
+	s := &e.low
+
+	// Block 1L, SUBTRA
+	el := saturate(xLow - s.s)
+
+	// Block 1L, QUANTL
+	wd := el
+	if el < 0 {
+		wd = -(el + 1)
+	}
+	i := 1
+	for ; i < 30; i++ {
+		if wd < (q6[i]*s.det)>>12 {
+			break
+		}
+	}
+	code := ilp[i]
+	if el < 0 {
+		code = iln[i]
+	}
+
+	// Block 2L, INVQAL
+	ril := code >> 2
+	dLow := (s.det * qm4[ril]) >> 15
+
+	// Block 3L, LOGSCL
+	s.nb = ((s.nb * 127) >> 7) + wl[rl42[ril]]
+	if s.nb < 0 {
+		s.nb = 0
+	} else if s.nb > 18432 {
+		s.nb = 18432
+	}
+
+	// Block 3L, SCALEL
+	s.det = scale(s.nb, 8)
+
+	s.update(dLow)
+	return code
+}
+
+func (e *adpcmEncoder) encodeHigh(xHigh int) int {
+	s := &e.high
+
+	// Block 1H, SUBTRA
+	eh := saturate(xHigh - s.s)
+
+	// Block 1H, QUANTH
+	wd := eh
+	if eh < 0 {
+		wd = -(eh + 1)
+	}
+	mih := 1
+	if wd >= (564*s.det)>>12 {
+		mih = 2
+	}
+	code := ihp[mih]
+	if eh < 0 {
+		code = ihn[mih]
+	}
+
+	// Block 2H, INVQAH
+	dHigh := (s.det * qm2[code]) >> 15
+
+	// Block 3H, LOGSCH
+	s.nb = ((s.nb * 127) >> 7) + wh[rh2[code]]
+	if s.nb < 0 {
+		s.nb = 0
+	} else if s.nb > 22528 {
+		s.nb = 22528
+	}
+
+	// Block 3H, SCALEH
+	s.det = scale(s.nb, 10)
+
+	s.update(dHigh)
+	return code
+}
+
+// scale converts the logarithmic quantizer scale factor to the linear domain.
+func scale(nb, shift int) int {
+	wd1 := (nb >> 6) & 31
+	wd2 := shift - (nb >> 11)
+	if wd2 < 0 {
+		return (ilb[wd1] << -wd2) << 2
+	}
+	return (ilb[wd1] >> wd2) << 2
+}
+
+// update adapts the predictor of the band to the given quantized difference signal
+// (block 4).
+func (s *band) update(d int) {
+	// RECONS
+	s.d[0] = d
+	s.r[0] = saturate(s.s + d)
+
+	// PARREC
+	s.p[0] = saturate(s.sz + d)
+
+	// UPPOL2
+	for i := 0; i < 3; i++ {
+		s.sg[i] = s.p[i] >> 15
+	}
+	wd1 := saturate(s.a[1] << 2)
+	wd2 := wd1
+	if s.sg[0] == s.sg[1] {
+		wd2 = -wd1
+	}
+	if wd2 > 32767 {
+		wd2 = 32767
+	}
+	wd3 := wd2 >> 7
+	if s.sg[0] == s.sg[2] {
+		wd3 += 128
+	} else {
+		wd3 -= 128
+	}
+	wd3 += (s.a[2] * 32512) >> 15
+	if wd3 > 12288 {
+		wd3 = 12288
+	} else if wd3 < -12288 {
+		wd3 = -12288
+	}
+	s.ap[2] = wd3
+
+	// UPPOL1
+	s.sg[0] = s.p[0] >> 15
+	s.sg[1] = s.p[1] >> 15
+	wd1 = -192
+	if s.sg[0] == s.sg[1] {
+		wd1 = 192
+	}
+	wd2 = (s.a[1] * 32640) >> 15
+	s.ap[1] = saturate(wd1 + wd2)
+	wd3 = saturate(15360 - s.ap[2])
+	if s.ap[1] > wd3 {
+		s.ap[1] = wd3
+	} else if s.ap[1] < -wd3 {
+		s.ap[1] = -wd3
+	}
+
+	// UPZERO
+	wd1 = 128
+	if d == 0 {
+		wd1 = 0
+	}
+	s.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		s.sg[i] = s.d[i] >> 15
+		wd2 = -wd1
+		if s.sg[i] == s.sg[0] {
+			wd2 = wd1
+		}
+		wd3 = (s.b[i] * 32640) >> 15
+		s.bp[i] = saturate(wd2 + wd3)
+	}
+
+	// DELAYA
+	for i := 6; i > 0; i-- {
+		s.d[i] = s.d[i-1]
+		s.b[i] = s.bp[i]
+	}
+	for i := 2; i > 0; i-- {
+		s.r[i] = s.r[i-1]
+		s.p[i] = s.p[i-1]
+		s.a[i] = s.ap[i]
+	}
+
+	// FILTEP
+	wd1 = (s.a[1] * saturate(s.r[1]+s.r[1])) >> 15
+	wd2 = (s.a[2] * saturate(s.r[2]+s.r[2])) >> 15
+	s.sp = saturate(wd1 + wd2)
+
+	// FILTEZ
+	s.sz = 0
+	for i := 6; i > 0; i-- {
+		s.sz += (s.b[i] * saturate(s.d[i]+s.d[i])) >> 15
+	}
+	s.sz = saturate(s.sz)
+
+	// PREDIC
+	s.s = saturate(s.sp + s.sz)
+}
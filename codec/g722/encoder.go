@@ -0,0 +1,84 @@
+// Package g722 contains the G.722 audio codec.
+package g722
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pkg/errors"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+	"github.com/edaniels/gostream/codec/internal/pcm"
+)
+
+// SampleRate is the only sample rate G.722 supports. Audio of other rates is resampled.
+const SampleRate = 16000
+
+// bitrate is the bitrate of the only G.722 mode supported.
+const bitrate = 64000
+
+var errEncoderClosed = errors.New("encoder closed")
+
+type encoder struct {
+	mu        sync.Mutex
+	buf       *pcm.Buffer
+	adpcm     *adpcmEncoder
+	frameSize int
+	closed    bool
+}
+
+// NewEncoder returns a 64 kbit/s G.722 encoder that encodes audio into frames of the
+// given latency. Audio is downmixed to mono and resampled to 16kHz. No encoder option is supported
+// other than a TargetBitrate of 64 kbit/s.
+func NewEncoder(latency time.Duration, opts ourcodec.AudioEncoderOptions, _ golog.Logger) (ourcodec.AudioEncoder, error) {
+	frameSize := int(time.Duration(SampleRate) * latency / time.Second)
+	// every encoded byte holds two samples.
+	frameSize -= frameSize % 2
+	if frameSize <= 0 {
+		return nil, errors.Errorf("unsupported g722 latency %v", latency)
+	}
+	if (opts.TargetBitrate != 0 && opts.TargetBitrate != bitrate) || opts.Complexity != 0 || opts.FEC || opts.DTX ||
+		opts.ExpectedPacketLossPercent != 0 || opts.Application != ourcodec.AudioApplicationDefault {
+		return nil, errors.Errorf(
+			"unsupported g722 encoder options: target bitrate %d, complexity %d, fec %t, dtx %t, expected packet loss %d%%, application %q",
+			opts.TargetBitrate, opts.Complexity, opts.FEC, opts.DTX, opts.ExpectedPacketLossPercent, opts.Application)
+	}
+	return &encoder{
+		buf:       pcm.NewBuffer(SampleRate),
+		adpcm:     newADPCMEncoder(),
+		frameSize: frameSize,
+	}, nil
+}
+
+// Encode buffers the given audio chunk and encodes every complete frame available. Since
+// G.722 frames are just consecutive pairs of samples, a chunk longer than a frame is encoded
+// into one longer frame rather than being left to build up in the buffer. The returned bool
+// indicates whether or not a frame was produced.
+func (e *encoder) Encode(_ context.Context, chunk wave.Audio) ([]byte, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, false, errEncoderClosed
+	}
+	e.buf.Write(chunk)
+	var encoded []byte
+	for frame, ok := e.buf.Next(e.frameSize); ok; frame, ok = e.buf.Next(e.frameSize) {
+		encodedFrame := make([]byte, len(frame)/2)
+		e.adpcm.encode(frame, encodedFrame)
+		encoded = append(encoded, encodedFrame...)
+	}
+	if encoded == nil {
+		return nil, false, nil
+	}
+	return encoded, true, nil
+}
+
+// Close releases the encoder.
+func (e *encoder) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+}
@@ -0,0 +1,99 @@
+package g722
+
+import (
+	"context"
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+func TestEncode(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	enc, err := NewEncoder(20*time.Millisecond, ourcodec.AudioEncoderOptions{}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	// 20ms of a 1kHz tone at 16kHz.
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 320, Channels: 1, SamplingRate: SampleRate})
+	var frames [][]byte
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 320; j++ {
+			chunk.SetInt16(j, 0, wave.Int16Sample(8000*math.Sin(2*math.Pi*1000*float64(j)/SampleRate)))
+		}
+		encoded, ready, err := enc.Encode(context.Background(), chunk)
+		test.That(t, err, test.ShouldBeNil)
+		if ready {
+			frames = append(frames, encoded)
+		}
+	}
+	test.That(t, len(frames), test.ShouldBeBetweenOrEqual, 4, 5)
+	for _, frame := range frames {
+		test.That(t, frame, test.ShouldHaveLength, 160)
+	}
+	// a tone should not encode to a constant.
+	last := frames[len(frames)-1]
+	var varied bool
+	for _, b := range last {
+		if b != last[0] {
+			varied = true
+		}
+	}
+	test.That(t, varied, test.ShouldBeTrue)
+
+	enc.Close()
+	_, _, err = enc.Encode(context.Background(), chunk)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestEncodeLongChunks(t *testing.T) {
+	enc, err := NewEncoder(20*time.Millisecond, ourcodec.AudioEncoderOptions{}, golog.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	// 50ms at a time is two and a half frames.
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 800, Channels: 1, SamplingRate: SampleRate})
+	var encodedLen int
+	for i := 0; i < 20; i++ {
+		encoded, ready, err := enc.Encode(context.Background(), chunk)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, ready, test.ShouldBeTrue)
+		test.That(t, len(encoded)%160, test.ShouldEqual, 0)
+		encodedLen += len(encoded)
+		// every complete frame is encoded so no more than a frame stays buffered.
+		test.That(t, enc.(*encoder).buf.Buffered(), test.ShouldBeLessThan, 320)
+	}
+	// all but the frame still being buffered is encoded and every byte holds two samples.
+	test.That(t, encodedLen, test.ShouldBeGreaterThanOrEqualTo, (20*800-320)/2)
+}
+
+func TestADPCMReference(t *testing.T) {
+	// a sawtooth with noise, encoded at 64 kbit/s by g722.Encode(samples, g722.Rate64000, 0) of
+	// github.com/gotranspile/g722@v0.0.0-20240123003956-384a1bb16a19, a Go translation of
+	// spandsp's G.722 encoder.
+	samples := make([]int16, 320)
+	seed := uint32(1)
+	for i := range samples {
+		seed = seed*1103515245 + 12345
+		noise := int(int16(seed>>16)) / 8
+		sawtooth := (i*400)%16000 - 8000
+		samples[i] = int16(sawtooth + noise)
+	}
+	expected, err := hex.DecodeString(
+		"378c2084208404060e90999b1e1c1d1e3aaeb03673b2ae346e970dd999547715" +
+			"9a9d7e3bf13fb672f0b7efb5ebd04999575b745abdbd1e7877ff747db0fdeaeb" +
+			"ee8a0ef7d51f593e9fb9f63e5adc7475b073ae76e88d0c5cd73d7915def6da72" +
+			"def63d72aef8746af68d0d5e545ebfff9d75983cdfebfbde2bdaecf1af8d10bf" +
+			"53bddcd9da9d6f4e6c9f7b715771b0fe2d8e10785359f9f9197d7e54f37d74be")
+	test.That(t, err, test.ShouldBeNil)
+
+	encoded := make([]byte, len(samples)/2)
+	newADPCMEncoder().encode(samples, encoded)
+	test.That(t, encoded, test.ShouldResemble, expected)
+}
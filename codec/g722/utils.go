@@ -0,0 +1,37 @@
+package g722
+
+import (
+	"time"
+
+	"github.com/edaniels/golog"
+
+	"github.com/edaniels/gostream"
+	"github.com/edaniels/gostream/codec"
+)
+
+// DefaultStreamConfig configures G.722 as the audio encoder for a stream.
+var DefaultStreamConfig gostream.StreamConfig
+
+func init() {
+	DefaultStreamConfig.AudioEncoderFactory = NewEncoderFactory()
+}
+
+// NewEncoderFactory returns a G.722 audio encoder factory.
+func NewEncoderFactory() codec.AudioEncoderFactory {
+	return &factory{}
+}
+
+type factory struct{}
+
+func (f *factory) New(
+	sampleRate, channelCount int,
+	latency time.Duration,
+	opts codec.AudioEncoderOptions,
+	logger golog.Logger,
+) (codec.AudioEncoder, error) {
+	return NewEncoder(latency, opts, logger)
+}
+
+func (f *factory) MIMEType() string {
+	return "audio/G722"
+}
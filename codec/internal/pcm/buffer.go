@@ -0,0 +1,163 @@
+// Package pcm contains helpers for encoders that consume 16-bit mono PCM at a fixed sample rate.
+package pcm
+
+import (
+	"math"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// A Buffer downmixes and resamples audio chunks of any format to 16-bit mono PCM
+// at a fixed sample rate and hands it out in fixed size frames. Resampling uses linear
+// interpolation which is adequate for the narrowband voice codecs it serves. Audio is
+// low-pass filtered before being downsampled so that frequencies the output sample rate
+// cannot represent do not alias into the band that it can.
+type Buffer struct {
+	sampleRate int
+
+	inRate int
+	// pos is the position of the next output sample in the input, relative to the
+	// start of carry.
+	pos     float64
+	carry   []float64
+	pending []int16
+	// filter holds the taps of the low-pass filter when downsampling and history the latest
+	// input samples it is applied to.
+	filter  []float64
+	history []float64
+}
+
+// NewBuffer returns a Buffer that produces audio at the given sample rate.
+func NewBuffer(sampleRate int) *Buffer {
+	return &Buffer{sampleRate: sampleRate}
+}
+
+// Write downmixes, resamples, and buffers the given chunk.
+func (b *Buffer) Write(chunk wave.Audio) {
+	info := chunk.ChunkInfo()
+	if info.SamplingRate != b.inRate {
+		b.inRate = info.SamplingRate
+		b.pos = 0
+		b.carry = b.carry[:0]
+		b.filter = nil
+		b.history = nil
+		if b.inRate > b.sampleRate {
+			b.filter = lowPassFilter(b.inRate, b.sampleRate)
+			b.history = make([]float64, 0, 2*len(b.filter))
+		}
+	}
+	if info.SamplingRate == 0 || info.Channels == 0 {
+		return
+	}
+
+	in := b.carry
+	for i := 0; i < info.Len; i++ {
+		var sum float64
+		for ch := 0; ch < info.Channels; ch++ {
+			sum += float64(wave.Float32SampleFormat.Convert(chunk.At(i, ch)).(wave.Float32Sample))
+		}
+		in = append(in, b.lowPass(sum/float64(info.Channels)))
+	}
+
+	step := float64(b.inRate) / float64(b.sampleRate)
+	for {
+		idx := int(b.pos)
+		if idx+1 >= len(in) {
+			break
+		}
+		frac := b.pos - float64(idx)
+		b.pending = append(b.pending, toInt16(in[idx]*(1-frac)+in[idx+1]*frac))
+		b.pos += step
+	}
+
+	consumed := int(b.pos)
+	if consumed > len(in) {
+		consumed = len(in)
+	}
+	b.carry = append(b.carry[:0], in[consumed:]...)
+	b.pos -= float64(consumed)
+}
+
+// lowPass returns the next output of the low-pass filter given the next input sample, or
+// the sample itself when not downsampling.
+func (b *Buffer) lowPass(sample float64) float64 {
+	if b.filter == nil {
+		return sample
+	}
+	if len(b.history) == cap(b.history) {
+		b.history = b.history[:copy(b.history, b.history[len(b.history)-len(b.filter)+1:])]
+	}
+	b.history = append(b.history, sample)
+
+	window := b.history
+	if len(window) > len(b.filter) {
+		window = window[len(window)-len(b.filter):]
+	}
+	// the taps are symmetric so they line up with the window in either order; samples from
+	// before the first one count as silence.
+	taps := b.filter[len(b.filter)-len(window):]
+	var filtered float64
+	for i, tap := range taps {
+		filtered += tap * window[i]
+	}
+	return filtered
+}
+
+// lowPassFilter returns the taps of a Hamming windowed sinc low-pass filter for audio at
+// inRate that removes the frequencies audio at outRate cannot represent. The filter gets
+// longer with the ratio of the rates to keep its transition band a fixed fraction of outRate.
+func lowPassFilter(inRate, outRate int) []float64 {
+	ratio := float64(inRate) / float64(outRate)
+	numTaps := int(math.Ceil(32*ratio)) | 1
+	// frequencies are in cycles per input sample. The transition band of a Hamming window
+	// is about 3.3 over the number of taps wide and ends at the output's Nyquist frequency.
+	transition := 3.3 / float64(numTaps)
+	cutoff := 0.5/ratio - transition/2
+
+	taps := make([]float64, numTaps)
+	middle := float64(numTaps-1) / 2
+	var sum float64
+	for i := range taps {
+		t := float64(i) - middle
+		sinc := 2 * cutoff
+		if t != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*t) / (math.Pi * t)
+		}
+		window := 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(numTaps-1))
+		taps[i] = sinc * window
+		sum += taps[i]
+	}
+	// normalize so that the filter leaves the level of low frequencies unchanged.
+	for i := range taps {
+		taps[i] /= sum
+	}
+	return taps
+}
+
+// Buffered returns how many samples are buffered.
+func (b *Buffer) Buffered() int {
+	return len(b.pending)
+}
+
+// Next returns the next frame of the given number of samples if enough audio has
+// been buffered.
+func (b *Buffer) Next(samples int) ([]int16, bool) {
+	if len(b.pending) < samples {
+		return nil, false
+	}
+	frame := make([]int16, samples)
+	copy(frame, b.pending)
+	b.pending = append(b.pending[:0], b.pending[samples:]...)
+	return frame, true
+}
+
+func toInt16(sample float64) int16 {
+	scaled := math.Round(sample * math.MaxInt16)
+	if scaled > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if scaled < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(scaled)
+}
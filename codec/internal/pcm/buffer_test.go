@@ -0,0 +1,48 @@
+package pcm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+)
+
+// resampledLevel returns the RMS level, relative to full scale, of a 48kHz tone of the given
+// frequency and amplitude after resampling to 8kHz.
+func resampledLevel(frequency, amplitude float64) float64 {
+	buf := NewBuffer(8000)
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 48000})
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 480; j++ {
+			sample := amplitude * math.Sin(2*math.Pi*frequency*float64(i*480+j)/48000)
+			chunk.SetFloat32(j, 0, wave.Float32Sample(sample))
+		}
+		buf.Write(chunk)
+	}
+
+	// skip the first frames while the filter fills up.
+	var sum float64
+	var count int
+	for i := 0; ; i++ {
+		frame, ok := buf.Next(80)
+		if !ok {
+			break
+		}
+		if i < 5 {
+			continue
+		}
+		for _, sample := range frame {
+			sum += math.Pow(float64(sample)/math.MaxInt16, 2)
+			count++
+		}
+	}
+	return math.Sqrt(sum / float64(count))
+}
+
+func TestBufferResamples(t *testing.T) {
+	// a tone the output can represent passes unchanged.
+	test.That(t, resampledLevel(1000, 0.5), test.ShouldAlmostEqual, 0.5/math.Sqrt2, 0.02)
+	// a tone above the output's Nyquist frequency is filtered out instead of aliasing to 2kHz.
+	test.That(t, resampledLevel(6000, 0.5), test.ShouldBeLessThan, 0.005)
+}
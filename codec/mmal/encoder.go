@@ -3,7 +3,10 @@ package mmal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"image"
+	"sync"
 
 	ourcodec "github.com/edaniels/gostream/codec"
 
@@ -14,17 +17,25 @@ import (
 )
 
 type encoder struct {
+	mu     sync.Mutex
 	codec  codec.ReadCloser
 	img    image.Image
 	logger golog.Logger
 }
 
-// Gives suitable results. Probably want to make this configurable this in the future.
-const bitrate = 3_200_000
+var errEncoderClosed = errors.New("encoder closed")
+
+// DefaultBitrate gives suitable results when no target bitrate is configured.
+const DefaultBitrate = 3_200_000
 
 // NewEncoder returns an MMAL encoder that can encode images of the given width and height. It will
-// also ensure that it produces key frames at the given interval.
-func NewEncoder(width, height, keyFrameInterval int, logger golog.Logger) (ourcodec.VideoEncoder, error) {
+// also ensure that it produces key frames at the given interval. MMAL only exposes the bitrate, so
+// TargetBitrate is the only encoder option supported; setting any other option is an error.
+func NewEncoder(
+	width, height, keyFrameInterval int,
+	opts ourcodec.VideoEncoderOptions,
+	logger golog.Logger,
+) (ourcodec.VideoEncoder, error) {
 	enc := &encoder{logger: logger}
 
 	var builder codec.VideoEncoderBuilder
@@ -33,8 +44,17 @@ func NewEncoder(width, height, keyFrameInterval int, logger golog.Logger) (ourco
 		return nil, err
 	}
 	builder = &params
-	params.BitRate = bitrate
+	params.BitRate = DefaultBitrate
+	if opts.TargetBitrate != 0 {
+		params.BitRate = opts.TargetBitrate
+	}
 	params.KeyFrameInterval = keyFrameInterval
+	if opts.MaxBitrate != 0 || opts.RateControl != ourcodec.RateControlDefault ||
+		opts.Profile != "" || opts.Level != "" || opts.SpeedPreset != "" || opts.ScalabilityMode != "" {
+		return nil, fmt.Errorf(
+			"unsupported MMAL encoder options: max bitrate %d, rate control %q, profile %q, level %q, speed preset %q, scalability mode %q",
+			opts.MaxBitrate, opts.RateControl, opts.Profile, opts.Level, opts.SpeedPreset, opts.ScalabilityMode)
+	}
 
 	codec, err := builder.BuildVideoEncoder(enc, prop.Media{
 		Video: prop.Video{
@@ -57,6 +77,11 @@ func (v *encoder) Read() (img image.Image, release func(), err error) {
 
 // Encode asks the codec to process the given image.
 func (v *encoder) Encode(_ context.Context, img image.Image) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.codec == nil {
+		return nil, errEncoderClosed
+	}
 	v.img = img
 	data, release, err := v.codec.Read()
 	dataCopy := make([]byte, len(data))
@@ -64,3 +89,16 @@ func (v *encoder) Encode(_ context.Context, img image.Image) ([]byte, error) {
 	release()
 	return dataCopy, err
 }
+
+// Close releases the underlying codec.
+func (v *encoder) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.codec == nil {
+		return
+	}
+	if err := v.codec.Close(); err != nil {
+		v.logger.Errorw("error closing mmal codec", "error", err)
+	}
+	v.codec = nil
+}
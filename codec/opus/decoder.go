@@ -0,0 +1,71 @@
+package opus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	hopus "gopkg.in/hraban/opus.v2"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+// maxFrameDurationMs is the longest duration of audio a single Opus packet can contain.
+const maxFrameDurationMs = 120
+
+type decoder struct {
+	mu           sync.Mutex
+	engine       *hopus.Decoder
+	sampleRate   int
+	channelCount int
+	pcm          []float32
+	logger       golog.Logger
+}
+
+// NewDecoder returns an Opus decoder that decodes audio into chunks of the given sample
+// rate and channel count.
+func NewDecoder(sampleRate, channelCount int, logger golog.Logger) (ourcodec.AudioDecoder, error) {
+	engine, err := hopus.NewDecoder(sampleRate, channelCount)
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{
+		engine:       engine,
+		sampleRate:   sampleRate,
+		channelCount: channelCount,
+		pcm:          make([]float32, channelCount*maxFrameDurationMs*sampleRate/1000),
+		logger:       logger,
+	}, nil
+}
+
+// Decode decodes a single Opus packet into interleaved float32 audio.
+func (d *decoder) Decode(_ context.Context, data []byte) (wave.Audio, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.engine == nil {
+		return nil, errDecoderClosed
+	}
+
+	numSamples, err := d.engine.DecodeFloat32(data, d.pcm)
+	if err != nil {
+		return nil, err
+	}
+	if numSamples == 0 {
+		return nil, nil
+	}
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{
+		Len:          numSamples,
+		Channels:     d.channelCount,
+		SamplingRate: d.sampleRate,
+	})
+	copy(chunk.Data, d.pcm[:numSamples*d.channelCount])
+	return chunk, nil
+}
+
+// Close releases the decoder.
+func (d *decoder) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.engine = nil
+}
@@ -0,0 +1,51 @@
+package opus
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	ctx := context.Background()
+	const sampleRate = 48000
+
+	enc, err := NewEncoder(sampleRate, 1, 20*time.Millisecond, ourcodec.AudioEncoderOptions{}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+	dec, err := NewDecoder(sampleRate, 1, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer dec.Close()
+
+	// 20ms of a 440Hz tone.
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 960, Channels: 1, SamplingRate: sampleRate})
+	var decoded []wave.Audio
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 960; j++ {
+			chunk.SetFloat32(j, 0, wave.Float32Sample(0.5*math.Sin(2*math.Pi*440*float64(i*960+j)/sampleRate)))
+		}
+		encoded, ready, err := enc.Encode(ctx, chunk)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, ready, test.ShouldBeTrue)
+		audio, err := dec.Decode(ctx, encoded)
+		test.That(t, err, test.ShouldBeNil)
+		decoded = append(decoded, audio)
+	}
+
+	last := decoded[len(decoded)-1]
+	test.That(t, last.ChunkInfo(), test.ShouldResemble, wave.ChunkInfo{Len: 960, Channels: 1, SamplingRate: sampleRate})
+	var energy float64
+	for i := 0; i < last.ChunkInfo().Len; i++ {
+		sample := float64(last.At(i, 0).(wave.Float32Sample))
+		energy += sample * sample
+	}
+	test.That(t, energy, test.ShouldBeGreaterThan, 1)
+}
@@ -3,140 +3,182 @@ package opus
 
 import (
 	"context"
-	"io"
 	"sync"
 	"time"
 
 	"github.com/edaniels/golog"
-	"github.com/pion/mediadevices/pkg/codec"
-	"github.com/pion/mediadevices/pkg/codec/opus"
-	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
-	"go.viam.com/utils"
+	"github.com/pkg/errors"
+	hopus "gopkg.in/hraban/opus.v2"
 
 	ourcodec "github.com/edaniels/gostream/codec"
 )
 
 type encoder struct {
-	codec                   codec.ReadCloser
-	chunkCh                 chan wave.Audio
-	encodedCh               chan encodedData
-	logger                  golog.Logger
-	cancelCtx               context.Context
-	cancelFunc              func()
-	activeBackgroundWorkers sync.WaitGroup
+	mu           sync.Mutex
+	engine       *hopus.Encoder
+	sampleRate   int
+	channelCount int
+	frameSize    int
+	pcm          []float32
+	encoded      []byte
+	logger       golog.Logger
 }
 
-// Gives suitable results. Probably want to make this configurable this in the future.
-const bitrate = 32000
+// DefaultBitrate gives suitable results when no target bitrate is configured.
+const DefaultBitrate = 32000
 
-type encodedData struct {
-	data []byte
-	err  error
+// defaultFECPacketLossPercent is the packet loss assumed when FEC is enabled without an
+// expected packet loss since Opus adds no redundancy when it expects no loss.
+const defaultFECPacketLossPercent = 10
+
+// applications maps audio applications to their Opus equivalent.
+var applications = map[ourcodec.AudioApplication]hopus.Application{
+	ourcodec.AudioApplicationDefault:  hopus.AppVoIP,
+	ourcodec.AudioApplicationVoIP:     hopus.AppVoIP,
+	ourcodec.AudioApplicationAudio:    hopus.AppAudio,
+	ourcodec.AudioApplicationLowDelay: hopus.AppRestrictedLowdelay,
 }
 
-// NewEncoder returns an Opus encoder that can encode images of the given width and height. It will
-// also ensure that it produces key frames at the given interval.
-func NewEncoder(sampleRate, channelCount int, latency time.Duration, logger golog.Logger) (ourcodec.AudioEncoder, error) {
-	cancelCtx, cancelFunc := context.WithCancel(context.Background())
-	enc := &encoder{
-		chunkCh:    make(chan wave.Audio, 1),
-		encodedCh:  make(chan encodedData, 1),
-		logger:     logger,
-		cancelCtx:  cancelCtx,
-		cancelFunc: cancelFunc,
+var (
+	errEncoderClosed = errors.New("encoder closed")
+	errDecoderClosed = errors.New("decoder closed")
+)
+
+// maxPacketSize is the largest Opus packet that we will produce. It's the recommended
+// max size from the libopus documentation.
+const maxPacketSize = 4000
+
+// validLatency returns whether or not the given latency is an allowed Opus frame duration.
+func validLatency(latency time.Duration) bool {
+	switch latency {
+	case 2500 * time.Microsecond,
+		5 * time.Millisecond,
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		60 * time.Millisecond:
+		return true
+	default:
+		return false
 	}
+}
 
-	var builder codec.AudioEncoderBuilder
-	params, err := opus.NewParams()
-	if err != nil {
-		return nil, err
+// NewEncoder returns an Opus encoder that can encode audio of the given sample rate and channel count
+// into frames of the given latency. Every chunk encoded must be exactly one frame long, which streams
+// guarantee by rechunking their audio; see gostream.NewRechunkAudioSource otherwise.
+func NewEncoder(
+	sampleRate, channelCount int,
+	latency time.Duration,
+	opts ourcodec.AudioEncoderOptions,
+	logger golog.Logger,
+) (ourcodec.AudioEncoder, error) {
+	if !validLatency(latency) {
+		return nil, errors.Errorf("unsupported opus latency %v", latency)
+	}
+
+	application, ok := applications[opts.Application]
+	if !ok {
+		return nil, errors.Errorf("unknown opus application %q", opts.Application)
 	}
-	builder = &params
-	params.BitRate = bitrate
-	params.Latency = opus.Latency(latency)
-
-	codec, err := builder.BuildAudioEncoder(enc, prop.Media{
-		Audio: prop.Audio{
-			Latency:      latency,
-			SampleRate:   sampleRate,
-			ChannelCount: channelCount,
-		},
-	})
+	if opts.ExpectedPacketLossPercent < 0 || opts.ExpectedPacketLossPercent > 100 {
+		return nil, errors.Errorf("invalid expected packet loss percent %d", opts.ExpectedPacketLossPercent)
+	}
+
+	engine, err := hopus.NewEncoder(sampleRate, channelCount, application)
 	if err != nil {
 		return nil, err
 	}
-	enc.codec = codec
 
-	enc.activeBackgroundWorkers.Add(1)
-	utils.ManagedGo(func() {
-		for {
-			if cancelCtx.Err() != nil {
-				return
-			}
-			data, release, err := enc.codec.Read()
-			dataCopy := make([]byte, len(data))
-			copy(dataCopy, data)
-			release()
-
-			select {
-			case <-cancelCtx.Done():
-				return
-			case enc.encodedCh <- encodedData{dataCopy, err}:
-			}
+	bitrate := DefaultBitrate
+	if opts.TargetBitrate != 0 {
+		bitrate = opts.TargetBitrate
+	}
+	if err := engine.SetBitrate(bitrate); err != nil {
+		return nil, err
+	}
+	if opts.Complexity != 0 {
+		if err := engine.SetComplexity(opts.Complexity); err != nil {
+			return nil, err
 		}
-	}, func() {
-		defer enc.activeBackgroundWorkers.Done()
-		close(enc.encodedCh)
-	})
+	}
+	if err := engine.SetInBandFEC(opts.FEC); err != nil {
+		return nil, err
+	}
+	packetLossPercent := opts.ExpectedPacketLossPercent
+	if opts.FEC && packetLossPercent == 0 {
+		packetLossPercent = defaultFECPacketLossPercent
+	}
+	if err := engine.SetPacketLossPerc(packetLossPercent); err != nil {
+		return nil, err
+	}
+	if err := engine.SetDTX(opts.DTX); err != nil {
+		return nil, err
+	}
 
-	return enc, nil
+	frameSize := int(time.Duration(sampleRate) * latency / time.Second)
+	return &encoder{
+		engine:       engine,
+		sampleRate:   sampleRate,
+		channelCount: channelCount,
+		frameSize:    frameSize,
+		pcm:          make([]float32, frameSize*channelCount),
+		encoded:      make([]byte, maxPacketSize),
+		logger:       logger,
+	}, nil
 }
 
-// Read returns an audio chunk for codec to process.
-func (a *encoder) Read() (chunk wave.Audio, release func(), err error) {
-	if err := a.cancelCtx.Err(); err != nil {
-		return nil, func() {}, err
+// Encode encodes the given audio chunk, which must be exactly one frame long, into a
+// single Opus packet. Nothing is buffered in between calls so every chunk in produces
+// exactly one packet out.
+func (a *encoder) Encode(_ context.Context, chunk wave.Audio) ([]byte, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.engine == nil {
+		return nil, false, errEncoderClosed
 	}
 
-	select {
-	case <-a.cancelCtx.Done():
-		return nil, func() {}, io.EOF
-	case chunk := <-a.chunkCh:
-		return chunk, func() {}, nil
+	info := chunk.ChunkInfo()
+	if info.SamplingRate != a.sampleRate || info.Channels != a.channelCount {
+		return nil, false, errors.Errorf(
+			"expected audio of %d Hz and %d channels but got %d Hz and %d channels",
+			a.sampleRate, a.channelCount, info.SamplingRate, info.Channels)
 	}
-}
-
-// Encode asks the codec to process the given audio chunk.
-func (a *encoder) Encode(ctx context.Context, chunk wave.Audio) ([]byte, bool, error) {
-	defer func() {
-		select {
-		case <-ctx.Done():
-			return
-		case <-a.cancelCtx.Done():
-			return
-		case a.chunkCh <- chunk:
+	if info.Len != a.frameSize {
+		return nil, false, errors.Errorf("expected chunk of %d samples but got %d", a.frameSize, info.Len)
+	}
+	if asFloat, ok := chunk.(*wave.Float32Interleaved); ok {
+		copy(a.pcm, asFloat.Data[:info.Len*info.Channels])
+	} else {
+		for i := 0; i < info.Len; i++ {
+			for ch := 0; ch < info.Channels; ch++ {
+				a.pcm[i*info.Channels+ch] = float32(wave.Float32SampleFormat.Convert(chunk.At(i, ch)).(wave.Float32Sample))
+			}
 		}
-	}()
-	if err := a.cancelCtx.Err(); err != nil {
+	}
+
+	n, err := a.engine.EncodeFloat32(a.pcm, a.encoded)
+	if err != nil {
 		return nil, false, err
 	}
-	select {
-	case <-ctx.Done():
-		return nil, false, ctx.Err()
-	case <-a.cancelCtx.Done():
-		return nil, false, a.cancelCtx.Err()
-	case encoded := <-a.encodedCh:
-		if encoded.err != nil {
-			return nil, false, encoded.err
-		}
-		return encoded.data, true, nil
-	default:
-		return nil, false, nil
+	encoded := make([]byte, n)
+	copy(encoded, a.encoded[:n])
+	return encoded, true, nil
+}
+
+// SetBitrate changes the target bitrate of the encoder starting with the next encoded frame.
+func (a *encoder) SetBitrate(bitrate int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.engine == nil {
+		return errEncoderClosed
 	}
+	return a.engine.SetBitrate(bitrate)
 }
 
+// Close releases the encoder.
 func (a *encoder) Close() {
-	a.cancelFunc()
-	a.activeBackgroundWorkers.Wait()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.engine = nil
 }
@@ -0,0 +1,60 @@
+package opus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/wave"
+	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+func TestEncoderOptions(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+
+	for _, app := range []ourcodec.AudioApplication{
+		ourcodec.AudioApplicationDefault,
+		ourcodec.AudioApplicationVoIP,
+		ourcodec.AudioApplicationAudio,
+		ourcodec.AudioApplicationLowDelay,
+	} {
+		enc, err := NewEncoder(48000, 2, 20*time.Millisecond, ourcodec.AudioEncoderOptions{
+			Application:               app,
+			FEC:                       true,
+			ExpectedPacketLossPercent: 20,
+			DTX:                       true,
+			Complexity:                5,
+		}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		enc.Close()
+	}
+
+	_, err := NewEncoder(48000, 2, 20*time.Millisecond, ourcodec.AudioEncoderOptions{Application: "karaoke"}, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewEncoder(48000, 2, 20*time.Millisecond, ourcodec.AudioEncoderOptions{ExpectedPacketLossPercent: 101}, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestEncodeOneInOneOut(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	ctx := context.Background()
+
+	enc, err := NewEncoder(48000, 1, 10*time.Millisecond, ourcodec.AudioEncoderOptions{}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 48000})
+	for i := 0; i < 5; i++ {
+		encoded, ready, err := enc.Encode(ctx, chunk)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, ready, test.ShouldBeTrue)
+		test.That(t, encoded, test.ShouldNotBeEmpty)
+	}
+
+	short := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 240, Channels: 1, SamplingRate: 48000})
+	_, _, err = enc.Encode(ctx, short)
+	test.That(t, err, test.ShouldNotBeNil)
+}
@@ -0,0 +1,106 @@
+package opus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/webrtc/v3"
+	"github.com/pkg/errors"
+	"go.viam.com/utils"
+
+	"github.com/edaniels/gostream"
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+type trackReader struct {
+	mu      sync.Mutex
+	track   *webrtc.TrackRemote
+	decoder ourcodec.AudioDecoder
+}
+
+// NewTrackAudioSource returns an audio source that decodes the Opus audio received on the
+// given remote track. Reading from the source ends with an error once the track ends
+// (e.g. the peer connection is closed).
+func NewTrackAudioSource(track *webrtc.TrackRemote) (gostream.AudioSource, error) {
+	if track.Kind() != webrtc.RTPCodecTypeAudio {
+		return nil, errors.Errorf("unsupported track kind %v", track.Kind())
+	}
+	codec := track.Codec()
+	if !strings.EqualFold(codec.MimeType, webrtc.MimeTypeOpus) {
+		return nil, errors.Errorf("unsupported track codec %q", codec.MimeType)
+	}
+	sampleRate := int(codec.ClockRate)
+	channels := int(codec.Channels)
+	if channels == 0 {
+		channels = 1
+	}
+
+	decoder, err := NewDecoder(sampleRate, channels, golog.Global())
+	if err != nil {
+		return nil, err
+	}
+	reader := &trackReader{
+		track:   track,
+		decoder: decoder,
+	}
+	return gostream.NewAudioSource(reader, prop.Audio{
+		ChannelCount:  channels,
+		SampleRate:    sampleRate,
+		SampleSize:    32,
+		IsFloat:       true,
+		IsInterleaved: true,
+	}), nil
+}
+
+// Read returns the audio decoded from the next non-empty RTP packet on the track. Reading
+// the track is interrupted if the given context is done.
+func (tr *trackReader) Read(ctx context.Context) (wave.Audio, func(), error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	// ReadRTP only returns early once its read deadline passes.
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(interrupted)
+		utils.UncheckedError(tr.track.SetReadDeadline(time.Now()))
+	})
+	defer func() {
+		if !stop() {
+			<-interrupted
+			utils.UncheckedError(tr.track.SetReadDeadline(time.Time{}))
+		}
+	}()
+
+	for {
+		packet, _, err := tr.track.ReadRTP()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			return nil, nil, err
+		}
+		if len(packet.Payload) == 0 {
+			continue
+		}
+
+		chunk, err := tr.decoder.Decode(ctx, packet.Payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		if chunk == nil {
+			continue
+		}
+		return chunk, func() {}, nil
+	}
+}
+
+// Close releases the decoder. The track itself is owned by its peer connection.
+func (tr *trackReader) Close(_ context.Context) error {
+	tr.decoder.Close()
+	return nil
+}
@@ -23,10 +23,30 @@ func NewEncoderFactory() codec.AudioEncoderFactory {
 
 type factory struct{}
 
-func (f *factory) New(sampleRate, channelCount int, latency time.Duration, logger golog.Logger) (codec.AudioEncoder, error) {
-	return NewEncoder(sampleRate, channelCount, latency, logger)
+func (f *factory) New(
+	sampleRate, channelCount int,
+	latency time.Duration,
+	opts codec.AudioEncoderOptions,
+	logger golog.Logger,
+) (codec.AudioEncoder, error) {
+	return NewEncoder(sampleRate, channelCount, latency, opts, logger)
 }
 
 func (f *factory) MIMEType() string {
 	return "audio/opus"
 }
+
+// NewDecoderFactory returns an Opus audio decoder factory.
+func NewDecoderFactory() codec.AudioDecoderFactory {
+	return &decoderFactory{}
+}
+
+type decoderFactory struct{}
+
+func (f *decoderFactory) New(sampleRate, channelCount int, logger golog.Logger) (codec.AudioDecoder, error) {
+	return NewDecoder(sampleRate, channelCount, logger)
+}
+
+func (f *decoderFactory) MIMEType() string {
+	return "audio/opus"
+}
@@ -0,0 +1,70 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Registry holds video and audio encoder factories keyed by their MIME type. The order
+// in which factories are registered is the order of preference when a codec is negotiated
+// with a peer.
+type Registry struct {
+	videoFactories []VideoEncoderFactory
+	audioFactories []AudioEncoderFactory
+}
+
+// NewRegistry returns a new, empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// RegisterVideoEncoderFactory adds the given factory to the registry. It is an error to
+// register two factories of the same MIME type.
+func (r *Registry) RegisterVideoEncoderFactory(factory VideoEncoderFactory) error {
+	if _, ok := r.VideoEncoderFactory(factory.MIMEType()); ok {
+		return fmt.Errorf("video encoder factory for %q already registered", factory.MIMEType())
+	}
+	r.videoFactories = append(r.videoFactories, factory)
+	return nil
+}
+
+// RegisterAudioEncoderFactory adds the given factory to the registry. It is an error to
+// register two factories of the same MIME type.
+func (r *Registry) RegisterAudioEncoderFactory(factory AudioEncoderFactory) error {
+	if _, ok := r.AudioEncoderFactory(factory.MIMEType()); ok {
+		return fmt.Errorf("audio encoder factory for %q already registered", factory.MIMEType())
+	}
+	r.audioFactories = append(r.audioFactories, factory)
+	return nil
+}
+
+// VideoEncoderFactory returns the video encoder factory for the given MIME type, if registered.
+func (r *Registry) VideoEncoderFactory(mimeType string) (VideoEncoderFactory, bool) {
+	return findFactory(r.videoFactories, mimeType)
+}
+
+// AudioEncoderFactory returns the audio encoder factory for the given MIME type, if registered.
+func (r *Registry) AudioEncoderFactory(mimeType string) (AudioEncoderFactory, bool) {
+	return findFactory(r.audioFactories, mimeType)
+}
+
+// VideoEncoderFactories returns all registered video encoder factories in order of preference.
+func (r *Registry) VideoEncoderFactories() []VideoEncoderFactory {
+	return append([]VideoEncoderFactory(nil), r.videoFactories...)
+}
+
+// AudioEncoderFactories returns all registered audio encoder factories in order of preference.
+func (r *Registry) AudioEncoderFactories() []AudioEncoderFactory {
+	return append([]AudioEncoderFactory(nil), r.audioFactories...)
+}
+
+// findFactory finds the factory of the given MIME type. MIME types are case-insensitive.
+func findFactory[T interface{ MIMEType() string }](factories []T, mimeType string) (T, bool) {
+	for _, factory := range factories {
+		if strings.EqualFold(factory.MIMEType(), mimeType) {
+			return factory, true
+		}
+	}
+	var zero T
+	return zero, false
+}
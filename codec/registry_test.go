@@ -0,0 +1,42 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/edaniels/golog"
+	"go.viam.com/test"
+
+	"github.com/edaniels/gostream/codec"
+)
+
+type fakeVideoEncoderFactory struct {
+	mimeType string
+}
+
+func (f fakeVideoEncoderFactory) New(_, _, _ int, _ codec.VideoEncoderOptions, _ golog.Logger) (codec.VideoEncoder, error) {
+	return nil, nil
+}
+
+func (f fakeVideoEncoderFactory) MIMEType() string {
+	return f.mimeType
+}
+
+func TestRegistry(t *testing.T) {
+	registry := codec.NewRegistry()
+	test.That(t, registry.RegisterVideoEncoderFactory(fakeVideoEncoderFactory{"video/VP9"}), test.ShouldBeNil)
+	test.That(t, registry.RegisterVideoEncoderFactory(fakeVideoEncoderFactory{"video/H264"}), test.ShouldBeNil)
+	test.That(t, registry.RegisterVideoEncoderFactory(fakeVideoEncoderFactory{"video/vp9"}), test.ShouldNotBeNil)
+
+	factory, ok := registry.VideoEncoderFactory("video/h264")
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, factory.MIMEType(), test.ShouldEqual, "video/H264")
+	_, ok = registry.VideoEncoderFactory("video/vp8")
+	test.That(t, ok, test.ShouldBeFalse)
+	_, ok = registry.AudioEncoderFactory("audio/opus")
+	test.That(t, ok, test.ShouldBeFalse)
+
+	factories := registry.VideoEncoderFactories()
+	test.That(t, factories, test.ShouldHaveLength, 2)
+	test.That(t, factories[0].MIMEType(), test.ShouldEqual, "video/VP9")
+	test.That(t, factories[1].MIMEType(), test.ShouldEqual, "video/H264")
+}
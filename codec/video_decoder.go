@@ -0,0 +1,23 @@
+package codec
+
+import (
+	"context"
+	"image"
+
+	"github.com/edaniels/golog"
+)
+
+// A VideoDecoder is anything that can decode bytes produced by a VideoEncoder of the same
+// type (see VideoDecoderFactory.MIMEType) back into images.
+type VideoDecoder interface {
+	// Decode decodes a single encoded frame. A nil image and nil error are returned if
+	// the data did not complete a displayable frame.
+	Decode(ctx context.Context, data []byte) (image.Image, error)
+	Close()
+}
+
+// A VideoDecoderFactory produces VideoDecoders and provides information about the underlying decoder itself.
+type VideoDecoderFactory interface {
+	New(logger golog.Logger) (VideoDecoder, error)
+	MIMEType() string
+}
@@ -16,12 +16,70 @@ const DefaultKeyFrameInterval = 30
 // A VideoEncoder is anything that can encode images into bytes. This means that
 // the encoder must follow some type of format dictated by a type (see EncoderFactory.MimeType).
 // An encoder that produces bytes of different encoding formats per call is invalid.
+//
+// Each call to Encode returns a single encoded frame, or nil if the encoder has nothing to
+// output yet, which streams packetize according to the MIME type:
+//   - video/H264 and video/H265: an access unit as an Annex B byte stream, i.e. NAL units
+//     each preceded by a start code.
+//   - video/AV1: a temporal unit of OBUs in the low overhead bitstream format, i.e. OBUs
+//     with their size fields set.
+//   - video/VP8 and video/VP9: a frame as the encoder outputs it.
 type VideoEncoder interface {
 	Encode(ctx context.Context, img image.Image) ([]byte, error)
+	Close()
 }
 
 // A VideoEncoderFactory produces VideoEncoders and provides information about the underlying encoder itself.
 type VideoEncoderFactory interface {
-	New(height, width, keyFrameInterval int, logger golog.Logger) (VideoEncoder, error)
+	New(width, height, keyFrameInterval int, opts VideoEncoderOptions, logger golog.Logger) (VideoEncoder, error)
 	MIMEType() string
 }
+
+// A RateControlMode determines how an encoder distributes bits across frames.
+type RateControlMode string
+
+// The set of rate control modes an encoder may support.
+const (
+	RateControlDefault RateControlMode = ""
+	RateControlVBR     RateControlMode = "vbr"
+	RateControlCBR     RateControlMode = "cbr"
+	RateControlCQ      RateControlMode = "cq"
+)
+
+// VideoEncoderOptions configures how a VideoEncoder encodes. The zero value of any
+// field leaves the encoder's default in place. Encoders fail to be created with options
+// they cannot honor; every codec package documents the options it supports.
+type VideoEncoderOptions struct {
+	// TargetBitrate is the average bitrate, in bits per second, the encoder aims for.
+	TargetBitrate int
+
+	// MaxBitrate is the bitrate, in bits per second, the encoder should not exceed.
+	MaxBitrate int
+
+	// RateControl is how the encoder should meet the target bitrate.
+	RateControl RateControlMode
+
+	// Profile and Level are codec specific (e.g. "baseline" and "3.1" for H264).
+	Profile string
+	Level   string
+
+	// SpeedPreset trades quality for encoding speed and is codec specific
+	// (e.g. "ultrafast" for x264 or "realtime" for vpx).
+	SpeedPreset string
+
+	// ScalabilityMode is the layering of the encoded stream as named in the WebRTC SVC
+	// specification (e.g. "L1T2" or "L1T3" for two or three temporal layers). Encoders that
+	// support it implement TemporalLayerEncoder.
+	ScalabilityMode string
+}
+
+// A TemporalLayerEncoder is a VideoEncoder that encodes frames into temporal layers. Frames of
+// a layer only depend on frames of the same or lower layers so that the frames of higher layers
+// can be dropped to reduce the frame rate and bitrate sent to a viewer without a second encode.
+type TemporalLayerEncoder interface {
+	VideoEncoder
+
+	// EncodeLayered encodes like Encode and also returns the temporal layer the encoded frame
+	// belongs to, starting with zero for the base layer.
+	EncodeLayered(ctx context.Context, img image.Image) ([]byte, int, error)
+}
@@ -0,0 +1,124 @@
+package vpx
+
+/*
+#cgo pkg-config: vpx
+#include <stdlib.h>
+#include <vpx/vpx_decoder.h>
+#include <vpx/vp8dx.h>
+
+static vpx_codec_err_t decoder_init(vpx_codec_ctx_t *ctx, int vp9) {
+	vpx_codec_iface_t *iface = vp9 ? vpx_codec_vp9_dx() : vpx_codec_vp8_dx();
+	return vpx_codec_dec_init(ctx, iface, NULL, 0);
+}
+
+static vpx_image_t *decoder_get_frame(vpx_codec_ctx_t *ctx) {
+	vpx_codec_iter_t iter = NULL;
+	vpx_image_t *img = NULL;
+	vpx_image_t *next;
+	// only the last frame of a superframe is displayed.
+	while ((next = vpx_codec_get_frame(ctx, &iter)) != NULL) {
+		img = next;
+	}
+	return img;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"unsafe"
+
+	"github.com/edaniels/golog"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+var errDecoderClosed = errors.New("decoder closed")
+
+type decoder struct {
+	mu     sync.Mutex
+	ctx    *C.vpx_codec_ctx_t
+	logger golog.Logger
+}
+
+// NewDecoder returns a vpx decoder of the given type.
+func NewDecoder(codecVersion Version, logger golog.Logger) (ourcodec.VideoDecoder, error) {
+	var vp9 C.int
+	switch codecVersion {
+	case Version8:
+	case Version9:
+		vp9 = 1
+	default:
+		return nil, fmt.Errorf("unsupported vpx version: %s", codecVersion)
+	}
+
+	ctx := (*C.vpx_codec_ctx_t)(C.calloc(1, C.sizeof_vpx_codec_ctx_t))
+	if ec := C.decoder_init(ctx, vp9); ec != C.VPX_CODEC_OK {
+		C.free(unsafe.Pointer(ctx))
+		return nil, fmt.Errorf("vpx_codec_dec_init failed (%d)", ec)
+	}
+	return &decoder{ctx: ctx, logger: logger}, nil
+}
+
+// Decode decodes a single vpx frame into an image.
+func (d *decoder) Decode(_ context.Context, data []byte) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ctx == nil {
+		return nil, errDecoderClosed
+	}
+
+	cData := C.CBytes(data)
+	defer C.free(cData)
+	if ec := C.vpx_codec_decode(d.ctx, (*C.uint8_t)(cData), C.uint(len(data)), nil, 0); ec != C.VPX_CODEC_OK {
+		return nil, fmt.Errorf("vpx_codec_decode failed (%d)", ec)
+	}
+
+	img := C.decoder_get_frame(d.ctx)
+	if img == nil {
+		return nil, nil
+	}
+	if img.fmt != C.VPX_IMG_FMT_I420 {
+		return nil, fmt.Errorf("unsupported vpx image format (%d)", img.fmt)
+	}
+	return toYCbCr(img), nil
+}
+
+// toYCbCr copies the given I420 vpx image into an image.YCbCr.
+func toYCbCr(img *C.vpx_image_t) *image.YCbCr {
+	width, height := int(img.d_w), int(img.d_h)
+	out := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio420)
+	copyPlane(out.Y, out.YStride, img.planes[C.VPX_PLANE_Y], int(img.stride[C.VPX_PLANE_Y]), width, height)
+	chromaWidth, chromaHeight := (width+1)/2, (height+1)/2
+	copyPlane(out.Cb, out.CStride, img.planes[C.VPX_PLANE_U], int(img.stride[C.VPX_PLANE_U]), chromaWidth, chromaHeight)
+	copyPlane(out.Cr, out.CStride, img.planes[C.VPX_PLANE_V], int(img.stride[C.VPX_PLANE_V]), chromaWidth, chromaHeight)
+	return out
+}
+
+func copyPlane(dst []byte, dstStride int, src *C.uchar, srcStride, width, height int) {
+	srcBytes := unsafe.Slice((*byte)(unsafe.Pointer(src)), srcStride*(height-1)+width)
+	for row := 0; row < height; row++ {
+		copy(dst[row*dstStride:row*dstStride+width], srcBytes[row*srcStride:row*srcStride+width])
+	}
+}
+
+// Close releases the decoder.
+func (d *decoder) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ctx == nil {
+		return
+	}
+	if ec := C.vpx_codec_destroy(d.ctx); ec != C.VPX_CODEC_OK {
+		d.logger.Errorw("error destroying vpx decoder", "error_code", int(ec))
+	}
+	C.free(unsafe.Pointer(d.ctx))
+	d.ctx = nil
+}
@@ -0,0 +1,75 @@
+package vpx
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"github.com/edaniels/golog"
+	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+// gradientImage returns an image whose brightness increases from left to right and moves
+// with the given offset so that consecutive frames differ.
+func gradientImage(width, height, offset int) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio420)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Y[y*img.YStride+x] = uint8(16 + (x*4+offset)%200)
+		}
+	}
+	for i := range img.Cb {
+		img.Cb[i] = 128
+		img.Cr[i] = 128
+	}
+	return img
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, codecVersion := range []Version{Version8, Version9} {
+		t.Run(string(codecVersion), func(t *testing.T) {
+			logger := golog.NewTestLogger(t)
+			ctx := context.Background()
+			const width, height = 64, 48
+
+			enc, err := NewEncoder(codecVersion, width, height, ourcodec.DefaultKeyFrameInterval, ourcodec.VideoEncoderOptions{}, logger)
+			test.That(t, err, test.ShouldBeNil)
+			defer enc.Close()
+			dec, err := NewDecoder(codecVersion, logger)
+			test.That(t, err, test.ShouldBeNil)
+			defer dec.Close()
+
+			for i := 0; i < 5; i++ {
+				src := gradientImage(width, height, i)
+				encoded, err := enc.Encode(ctx, src)
+				test.That(t, err, test.ShouldBeNil)
+				test.That(t, encoded, test.ShouldNotBeEmpty)
+
+				decoded, err := dec.Decode(ctx, encoded)
+				test.That(t, err, test.ShouldBeNil)
+				test.That(t, decoded, test.ShouldNotBeNil)
+				test.That(t, decoded.Bounds(), test.ShouldResemble, src.Bounds())
+
+				yuv, ok := decoded.(*image.YCbCr)
+				test.That(t, ok, test.ShouldBeTrue)
+				var diff int
+				for y := 0; y < height; y++ {
+					for x := 0; x < width; x++ {
+						d := int(yuv.Y[y*yuv.YStride+x]) - int(src.Y[y*src.YStride+x])
+						if d < 0 {
+							d = -d
+						}
+						diff += d
+					}
+				}
+				test.That(t, float64(diff)/(width*height), test.ShouldBeLessThan, 8)
+			}
+
+			dec.Close()
+			_, err = dec.Decode(ctx, []byte{1})
+			test.That(t, err, test.ShouldBeError, errDecoderClosed)
+		})
+	}
+}
@@ -2,24 +2,16 @@
 package vpx
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	"image"
+	"time"
 
 	"github.com/edaniels/golog"
-	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/codec/vpx"
-	"github.com/pion/mediadevices/pkg/prop"
 
 	ourcodec "github.com/edaniels/gostream/codec"
 )
 
-type encoder struct {
-	codec  codec.ReadCloser
-	img    image.Image
-	logger golog.Logger
-}
-
 // Version determines the version of a vpx codec.
 type Version string
 
@@ -29,61 +21,97 @@ const (
 	Version9 Version = "vp9"
 )
 
-// Gives suitable results. Probably want to make this configurable this in the future.
-const bitrate = 3_200_000
+// DefaultBitrate gives suitable results when no target bitrate is configured.
+const DefaultBitrate = 3_200_000
 
-// NewEncoder returns a vpx encoder of the given type that can encode images of the given width and height. It will
-// also ensure that it produces key frames at the given interval.
-func NewEncoder(codecVersion Version, width, height, keyFrameInterval int, logger golog.Logger) (ourcodec.VideoEncoder, error) {
-	enc := &encoder{logger: logger}
+var errEncoderClosed = errors.New("encoder closed")
+
+// rateControlModes maps rate control modes to their vpx equivalent.
+var rateControlModes = map[ourcodec.RateControlMode]vpx.RateControlMode{
+	ourcodec.RateControlVBR: vpx.RateControlVBR,
+	ourcodec.RateControlCBR: vpx.RateControlCBR,
+	ourcodec.RateControlCQ:  vpx.RateControlCQ,
+}
 
-	var builder codec.VideoEncoderBuilder
+// deadlines maps speed preset names to the vpx deadline they correspond to.
+var deadlines = map[string]time.Duration{
+	"realtime": time.Microsecond,
+	"good":     time.Second,
+	"best":     0,
+}
+
+// NewEncoder returns a vpx encoder of the given type that can encode images of the given width and height. It will
+// also ensure that it produces key frames at the given interval. Every encoder option but Profile and Level is
+// supported; setting either is an error. The bitrate can be changed while encoding without a key frame.
+func NewEncoder(
+	codecVersion Version,
+	width, height, keyFrameInterval int,
+	opts ourcodec.VideoEncoderOptions,
+	logger golog.Logger,
+) (ourcodec.VideoEncoder, error) {
+	var params vpx.Params
 	switch codecVersion {
 	case Version8:
-		params, err := vpx.NewVP8Params()
+		vp8Params, err := vpx.NewVP8Params()
 		if err != nil {
 			return nil, err
 		}
-		builder = &params
-		params.BitRate = bitrate
-		params.KeyFrameInterval = keyFrameInterval
+		params = vp8Params.Params
 	case Version9:
-		params, err := vpx.NewVP9Params()
+		vp9Params, err := vpx.NewVP9Params()
 		if err != nil {
 			return nil, err
 		}
-		builder = &params
-		params.BitRate = bitrate
-		params.KeyFrameInterval = keyFrameInterval
+		params = vp9Params.Params
 	default:
 		return nil, fmt.Errorf("unsupported vpx version: %s", codecVersion)
 	}
-
-	codec, err := builder.BuildVideoEncoder(enc, prop.Media{
-		Video: prop.Video{
-			Width:  width,
-			Height: height,
-		},
-	})
-	if err != nil {
+	if err := applyOptions(&params, keyFrameInterval, opts); err != nil {
 		return nil, err
 	}
-	enc.codec = codec
-
-	return enc, nil
+	return newLayeredEncoder(codecVersion, params, width, height, opts, logger)
 }
 
-// Read returns an image for codec to process.
-func (v *encoder) Read() (img image.Image, release func(), err error) {
-	return v.img, nil, nil
+// applyOptions configures the vpx parameters from the given options.
+func applyOptions(params *vpx.Params, keyFrameInterval int, opts ourcodec.VideoEncoderOptions) error {
+	targetBitrate := DefaultBitrate
+	if opts.TargetBitrate != 0 {
+		targetBitrate = opts.TargetBitrate
+	}
+	if err := applyBitrate(params, targetBitrate, opts.MaxBitrate); err != nil {
+		return err
+	}
+	params.KeyFrameInterval = keyFrameInterval
+	if opts.RateControl != ourcodec.RateControlDefault {
+		mode, ok := rateControlModes[opts.RateControl]
+		if !ok {
+			return fmt.Errorf("unknown vpx rate control mode %q", opts.RateControl)
+		}
+		params.RateControlEndUsage = mode
+	}
+	if opts.SpeedPreset != "" {
+		deadline, ok := deadlines[opts.SpeedPreset]
+		if !ok {
+			return fmt.Errorf("unknown vpx speed preset %q", opts.SpeedPreset)
+		}
+		params.Deadline = deadline
+	}
+	if opts.Profile != "" || opts.Level != "" {
+		return fmt.Errorf("unsupported vpx encoder options: profile %q, level %q", opts.Profile, opts.Level)
+	}
+	return nil
 }
 
-// Encode asks the codec to process the given image.
-func (v *encoder) Encode(_ context.Context, img image.Image) ([]byte, error) {
-	v.img = img
-	data, release, err := v.codec.Read()
-	dataCopy := make([]byte, len(data))
-	copy(dataCopy, data)
-	release()
-	return dataCopy, err
+// applyBitrate configures the vpx parameters for the given target and max bitrate. A max
+// bitrate of zero leaves the overshoot unbounded by us.
+func applyBitrate(params *vpx.Params, targetBitrate, maxBitrate int) error {
+	params.BitRate = targetBitrate
+	if maxBitrate != 0 {
+		if maxBitrate < targetBitrate {
+			return fmt.Errorf("max bitrate %d is less than target bitrate %d", maxBitrate, targetBitrate)
+		}
+		// vpx expresses the max bitrate as how far above the target it may go.
+		params.RateControlOvershootPercent = uint((maxBitrate - targetBitrate) * 100 / targetBitrate)
+	}
+	return nil
 }
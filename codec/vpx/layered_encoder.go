@@ -0,0 +1,345 @@
+package vpx
+
+/*
+#cgo pkg-config: vpx
+#include <stdlib.h>
+#include <vpx/vpx_encoder.h>
+#include <vpx/vp8cx.h>
+
+static vpx_codec_iface_t *encoder_iface(int vp9) {
+	return vp9 ? vpx_codec_vp9_cx() : vpx_codec_vp8_cx();
+}
+
+static vpx_codec_err_t encoder_init(vpx_codec_ctx_t *ctx, vpx_codec_enc_cfg_t *cfg, int vp9) {
+	return vpx_codec_enc_init(ctx, encoder_iface(vp9), cfg, 0);
+}
+
+static const void *pkt_buf(const vpx_codec_cx_pkt_t *pkt) {
+	return pkt->data.frame.buf;
+}
+
+static int pkt_sz(const vpx_codec_cx_pkt_t *pkt) {
+	return (int)pkt->data.frame.sz;
+}
+
+static int pkt_is_key(const vpx_codec_cx_pkt_t *pkt) {
+	return (pkt->data.frame.flags & VPX_FRAME_IS_KEY) != 0;
+}
+
+// encoder_encode points the image at the given planes only for the duration of the call
+// so that libvpx never holds on to Go memory.
+static vpx_codec_err_t encoder_encode(
+	vpx_codec_ctx_t *ctx, vpx_image_t *img,
+	unsigned char *y, unsigned char *u, unsigned char *v, int y_stride, int c_stride,
+	vpx_codec_pts_t pts, unsigned long duration, vpx_enc_frame_flags_t flags, unsigned long deadline) {
+	img->planes[VPX_PLANE_Y] = y;
+	img->planes[VPX_PLANE_U] = u;
+	img->planes[VPX_PLANE_V] = v;
+	img->stride[VPX_PLANE_Y] = y_stride;
+	img->stride[VPX_PLANE_U] = c_stride;
+	img->stride[VPX_PLANE_V] = c_stride;
+	vpx_codec_err_t ret = vpx_codec_encode(ctx, img, pts, duration, flags, deadline);
+	img->planes[VPX_PLANE_Y] = img->planes[VPX_PLANE_U] = img->planes[VPX_PLANE_V] = NULL;
+	return ret;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/codec/vpx"
+	"github.com/pion/mediadevices/pkg/io/video"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+// A temporalPattern is the repeating sequence of temporal layers frames are encoded into
+// along with the reference flags that keep each layer independent of the layers above it.
+type temporalPattern struct {
+	layers []int
+	flags  []C.vpx_enc_frame_flags_t
+}
+
+const (
+	// baseLayerFlags makes base layer frames reference and update only the last frame buffer.
+	baseLayerFlags = C.VP8_EFLAG_NO_REF_GF | C.VP8_EFLAG_NO_REF_ARF | C.VP8_EFLAG_NO_UPD_GF | C.VP8_EFLAG_NO_UPD_ARF
+	// middleLayerFlags makes frames of the middle layer reference the base layer and keep the
+	// golden frame buffer for the top layer.
+	middleLayerFlags = C.VP8_EFLAG_NO_REF_GF | C.VP8_EFLAG_NO_REF_ARF | C.VP8_EFLAG_NO_UPD_LAST | C.VP8_EFLAG_NO_UPD_ARF
+	// topLayerFlags makes frames of the top layer reference lower layers without updating any
+	// frame buffer so that nothing ever depends on them.
+	topLayerFlags = C.VP8_EFLAG_NO_REF_ARF | C.VP8_EFLAG_NO_UPD_LAST | C.VP8_EFLAG_NO_UPD_GF | C.VP8_EFLAG_NO_UPD_ARF
+	// syncTopLayerFlags is like topLayerFlags but only references the base layer so that a
+	// viewer can start receiving the top layer right after any base layer frame.
+	syncTopLayerFlags = topLayerFlags | C.VP8_EFLAG_NO_REF_GF
+)
+
+// temporalPatterns maps scalability modes to the temporal pattern they use. Every frame
+// following a base layer frame only references frames from that point on, which lets viewers
+// switch layers at any base layer frame. L1T1, a single layer, is used when no scalability
+// mode is set.
+var temporalPatterns = map[string]temporalPattern{
+	"L1T1": {
+		layers: []int{0},
+		flags:  []C.vpx_enc_frame_flags_t{0},
+	},
+	"L1T2": {
+		layers: []int{0, 1},
+		flags:  []C.vpx_enc_frame_flags_t{baseLayerFlags, syncTopLayerFlags},
+	},
+	"L1T3": {
+		layers: []int{0, 2, 1, 2},
+		flags:  []C.vpx_enc_frame_flags_t{baseLayerFlags, syncTopLayerFlags, middleLayerFlags, topLayerFlags},
+	},
+}
+
+type layeredEncoder struct {
+	mu            sync.Mutex
+	ctx           *C.vpx_codec_ctx_t
+	cfg           *C.vpx_codec_enc_cfg_t
+	raw           *C.vpx_image_t
+	reader        video.Reader
+	params        vpx.Params
+	pattern       temporalPattern
+	patternIdx    int
+	keyFrameForce bool
+	sinceKeyFrame int
+	maxBitrate    int
+	start         time.Time
+	lastFrame     time.Time
+	img           image.Image
+	logger        golog.Logger
+}
+
+// newLayeredEncoder returns a vpx encoder that encodes frames into the temporal layers of the
+// given scalability mode. pion's vpx codec can neither set per frame flags nor change the
+// bitrate without being rebuilt, which forces a key frame, so libvpx is used directly.
+func newLayeredEncoder(
+	codecVersion Version,
+	params vpx.Params,
+	width, height int,
+	opts ourcodec.VideoEncoderOptions,
+	logger golog.Logger,
+) (ourcodec.VideoEncoder, error) {
+	scalabilityMode := opts.ScalabilityMode
+	if scalabilityMode == "" {
+		scalabilityMode = "L1T1"
+	}
+	pattern, ok := temporalPatterns[scalabilityMode]
+	if !ok {
+		return nil, fmt.Errorf("unsupported vpx scalability mode %q", opts.ScalabilityMode)
+	}
+	var vp9 C.int
+	if codecVersion == Version9 {
+		vp9 = 1
+	}
+
+	cfg := (*C.vpx_codec_enc_cfg_t)(C.calloc(1, C.sizeof_vpx_codec_enc_cfg_t))
+	if ec := C.vpx_codec_enc_config_default(C.encoder_iface(vp9), cfg, 0); ec != C.VPX_CODEC_OK {
+		C.free(unsafe.Pointer(cfg))
+		return nil, fmt.Errorf("vpx_codec_enc_config_default failed (%d)", ec)
+	}
+	cfg.g_w = C.uint(width)
+	cfg.g_h = C.uint(height)
+	cfg.g_timebase.num = 1
+	cfg.g_timebase.den = 1000
+	cfg.g_pass = C.VPX_RC_ONE_PASS
+	// frames must come out as soon as they go in to be assigned the right layer.
+	cfg.g_lag_in_frames = 0
+	cfg.g_error_resilient = C.VPX_ERROR_RESILIENT_DEFAULT
+	cfg.rc_resize_allowed = 0
+	cfg.rc_end_usage = uint32(params.RateControlEndUsage)
+	cfg.rc_undershoot_pct = C.uint(params.RateControlUndershootPercent)
+	cfg.rc_min_quantizer = C.uint(params.RateControlMinQuantizer)
+	cfg.rc_max_quantizer = C.uint(params.RateControlMaxQuantizer)
+	// key frames refresh every reference so they must start the pattern at the base layer;
+	// rather than letting libvpx place them anywhere, they are forced at the right frame.
+	cfg.kf_mode = C.VPX_KF_DISABLED
+	configureBitrate(cfg, &params)
+
+	ctx := (*C.vpx_codec_ctx_t)(C.calloc(1, C.sizeof_vpx_codec_ctx_t))
+	if ec := C.encoder_init(ctx, cfg, vp9); ec != C.VPX_CODEC_OK {
+		C.free(unsafe.Pointer(ctx))
+		C.free(unsafe.Pointer(cfg))
+		return nil, fmt.Errorf("vpx_codec_enc_init failed (%d)", ec)
+	}
+
+	// only the image's parameters are kept; its planes are set on every encode.
+	raw := (*C.vpx_image_t)(C.calloc(1, C.sizeof_vpx_image_t))
+	allocated := C.vpx_img_alloc(nil, C.VPX_IMG_FMT_I420, cfg.g_w, cfg.g_h, 1)
+	if allocated == nil {
+		C.vpx_codec_destroy(ctx)
+		C.free(unsafe.Pointer(ctx))
+		C.free(unsafe.Pointer(cfg))
+		C.free(unsafe.Pointer(raw))
+		return nil, errors.New("vpx_img_alloc failed")
+	}
+	*raw = *allocated
+	C.vpx_img_free(allocated)
+	raw.img_data = nil
+	raw.img_data_owner = 0
+	raw.self_allocd = 0
+
+	enc := &layeredEncoder{
+		ctx:        ctx,
+		cfg:        cfg,
+		raw:        raw,
+		params:     params,
+		pattern:    pattern,
+		maxBitrate: opts.MaxBitrate,
+		logger:     logger,
+	}
+	enc.reader = video.ToI420(enc)
+	return enc, nil
+}
+
+// configureBitrate copies the bitrate of the given parameters into the libvpx config.
+func configureBitrate(cfg *C.vpx_codec_enc_cfg_t, params *vpx.Params) {
+	cfg.rc_target_bitrate = C.uint(params.BitRate / 1000)
+	cfg.rc_overshoot_pct = C.uint(params.RateControlOvershootPercent)
+}
+
+// Read returns an image for the I420 converter to process.
+func (l *layeredEncoder) Read() (img image.Image, release func(), err error) {
+	return l.img, func() {}, nil
+}
+
+// Encode encodes the given image, discarding the temporal layer it belongs to.
+func (l *layeredEncoder) Encode(ctx context.Context, img image.Image) ([]byte, error) {
+	data, _, err := l.EncodeLayered(ctx, img)
+	return data, err
+}
+
+// EncodeLayered encodes the given image into the next temporal layer of the pattern.
+func (l *layeredEncoder) EncodeLayered(_ context.Context, img image.Image) ([]byte, int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ctx == nil {
+		return nil, 0, errEncoderClosed
+	}
+
+	l.img = img
+	converted, release, err := l.reader.Read()
+	l.img = nil
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+	yuvImg := converted.(*image.YCbCr)
+	if bounds := yuvImg.Bounds(); bounds.Dx() != int(l.cfg.g_w) || bounds.Dy() != int(l.cfg.g_h) {
+		return nil, 0, fmt.Errorf(
+			"expected image of %dx%d but got %dx%d", l.cfg.g_w, l.cfg.g_h, bounds.Dx(), bounds.Dy())
+	}
+
+	now := time.Now()
+	if l.start.IsZero() {
+		l.start = now
+		l.lastFrame = now
+	}
+	// vpx rejects a zero duration.
+	duration := now.Sub(l.lastFrame).Milliseconds()
+	if duration == 0 {
+		duration = 1
+	}
+
+	flags := l.pattern.flags[l.patternIdx]
+	layer := l.pattern.layers[l.patternIdx]
+	if l.params.KeyFrameInterval > 0 && l.sinceKeyFrame >= l.params.KeyFrameInterval && l.patternIdx == 0 {
+		l.keyFrameForce = true
+	}
+	if l.keyFrameForce {
+		flags |= C.VPX_EFLAG_FORCE_KF
+	}
+	if ec := C.encoder_encode(
+		l.ctx, l.raw,
+		(*C.uchar)(&yuvImg.Y[0]), (*C.uchar)(&yuvImg.Cb[0]), (*C.uchar)(&yuvImg.Cr[0]),
+		C.int(yuvImg.YStride), C.int(yuvImg.CStride),
+		C.vpx_codec_pts_t(now.Sub(l.start).Milliseconds()), C.ulong(duration), flags,
+		C.ulong(l.params.Deadline/time.Microsecond),
+	); ec != C.VPX_CODEC_OK {
+		return nil, 0, fmt.Errorf("vpx_codec_encode failed (%d)", ec)
+	}
+	l.keyFrameForce = false
+	l.lastFrame = now
+	l.patternIdx = (l.patternIdx + 1) % len(l.pattern.layers)
+	l.sinceKeyFrame++
+
+	var encoded []byte
+	var iter C.vpx_codec_iter_t
+	for {
+		pkt := C.vpx_codec_get_cx_data(l.ctx, &iter)
+		if pkt == nil {
+			break
+		}
+		if pkt.kind == C.VPX_CODEC_CX_FRAME_PKT {
+			if C.pkt_is_key(pkt) != 0 {
+				l.sinceKeyFrame = 0
+			}
+			encoded = append(encoded, C.GoBytes(C.pkt_buf(pkt), C.pkt_sz(pkt))...)
+		}
+	}
+	return encoded, layer, nil
+}
+
+// SetBitrate changes the target bitrate of the encoder starting with the next encoded frame.
+// The encoder is reconfigured in place so no key frame is forced.
+func (l *layeredEncoder) SetBitrate(bitrate int) error {
+	if bitrate <= 0 {
+		return fmt.Errorf("invalid bitrate %d", bitrate)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ctx == nil {
+		return errEncoderClosed
+	}
+
+	params := l.params
+	if err := applyBitrate(&params, bitrate, l.maxBitrate); err != nil {
+		return err
+	}
+	prevCfg := *l.cfg
+	configureBitrate(l.cfg, &params)
+	if ec := C.vpx_codec_enc_config_set(l.ctx, l.cfg); ec != C.VPX_CODEC_OK {
+		*l.cfg = prevCfg
+		return fmt.Errorf("vpx_codec_enc_config_set failed (%d)", ec)
+	}
+	l.params = params
+	return nil
+}
+
+// ForceKeyFrame forces the next encoded frame to be a key frame. The temporal pattern
+// restarts at the base layer since a key frame is always part of it.
+func (l *layeredEncoder) ForceKeyFrame() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ctx == nil {
+		return errEncoderClosed
+	}
+	l.keyFrameForce = true
+	l.patternIdx = 0
+	return nil
+}
+
+// Close releases the libvpx encoder.
+func (l *layeredEncoder) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ctx == nil {
+		return
+	}
+	if ec := C.vpx_codec_destroy(l.ctx); ec != C.VPX_CODEC_OK {
+		l.logger.Errorw("error destroying vpx encoder", "error_code", int(ec))
+	}
+	C.free(unsafe.Pointer(l.ctx))
+	C.free(unsafe.Pointer(l.cfg))
+	C.free(unsafe.Pointer(l.raw))
+	l.ctx = nil
+}
@@ -0,0 +1,76 @@
+package vpx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edaniels/golog"
+	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
+)
+
+// isVP8KeyFrame returns whether or not the given VP8 frame is a key frame, which the first
+// bit of its frame tag tells.
+func isVP8KeyFrame(frame []byte) bool {
+	return len(frame) > 0 && frame[0]&0x01 == 0
+}
+
+func TestTemporalLayers(t *testing.T) {
+	for _, tc := range []struct {
+		scalabilityMode string
+		layers          []int
+	}{
+		{"", []int{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"L1T2", []int{0, 1, 0, 1, 0, 1, 0, 1}},
+		{"L1T3", []int{0, 2, 1, 2, 0, 2, 1, 2}},
+	} {
+		t.Run(tc.scalabilityMode, func(t *testing.T) {
+			logger := golog.NewTestLogger(t)
+			enc, err := NewEncoder(Version8, 64, 48, 0, ourcodec.VideoEncoderOptions{ScalabilityMode: tc.scalabilityMode}, logger)
+			test.That(t, err, test.ShouldBeNil)
+			defer enc.Close()
+			layered, ok := enc.(ourcodec.TemporalLayerEncoder)
+			test.That(t, ok, test.ShouldBeTrue)
+
+			var layers []int
+			for i := range tc.layers {
+				encoded, layer, err := layered.EncodeLayered(context.Background(), gradientImage(64, 48, i))
+				test.That(t, err, test.ShouldBeNil)
+				test.That(t, isVP8KeyFrame(encoded), test.ShouldEqual, i == 0)
+				layers = append(layers, layer)
+			}
+			test.That(t, layers, test.ShouldResemble, tc.layers)
+
+			// a forced key frame restarts the pattern at the base layer.
+			test.That(t, enc.(ourcodec.KeyFrameController).ForceKeyFrame(), test.ShouldBeNil)
+			encoded, layer, err := layered.EncodeLayered(context.Background(), gradientImage(64, 48, 0))
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, isVP8KeyFrame(encoded), test.ShouldBeTrue)
+			test.That(t, layer, test.ShouldEqual, 0)
+		})
+	}
+
+	_, err := NewEncoder(Version8, 64, 48, 0, ourcodec.VideoEncoderOptions{ScalabilityMode: "L3T3"}, golog.NewTestLogger(t))
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestSetBitrate(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	enc, err := NewEncoder(Version8, 64, 48, 0, ourcodec.VideoEncoderOptions{}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	encoded, err := enc.Encode(context.Background(), gradientImage(64, 48, 0))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, isVP8KeyFrame(encoded), test.ShouldBeTrue)
+
+	// changing the bitrate does not cost a key frame.
+	test.That(t, enc.(ourcodec.BitrateController).SetBitrate(500_000), test.ShouldBeNil)
+	encoded, err = enc.Encode(context.Background(), gradientImage(64, 48, 1))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, encoded, test.ShouldNotBeEmpty)
+	test.That(t, isVP8KeyFrame(encoded), test.ShouldBeFalse)
+
+	test.That(t, enc.(ourcodec.BitrateController).SetBitrate(0), test.ShouldNotBeNil)
+}
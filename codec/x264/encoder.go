@@ -1,66 +1,357 @@
 // Package x264 contains the x264 video codec.
 package x264
 
+/*
+#cgo pkg-config: x264
+#include <stdint.h>
+#include <stdlib.h>
+#include <x264.h>
+
+static x264_t *encoder_open(x264_param_t *param) {
+	return x264_encoder_open(param);
+}
+
+// encoder_encode points the picture at the given planes only for the duration of the call
+// so that libx264 never holds on to Go memory. All NAL units of a frame are contiguous so
+// the start of the first is returned along with the size of the frame.
+static int encoder_encode(
+	x264_t *h, x264_picture_t *pic,
+	uint8_t *y, uint8_t *u, uint8_t *v, int y_stride, int c_stride,
+	int64_t pts, int force_key_frame, uint8_t **payload) {
+	x264_nal_t *nal;
+	int i_nal;
+	x264_picture_t pic_out;
+	pic->img.plane[0] = y;
+	pic->img.plane[1] = u;
+	pic->img.plane[2] = v;
+	pic->img.i_stride[0] = y_stride;
+	pic->img.i_stride[1] = c_stride;
+	pic->img.i_stride[2] = c_stride;
+	pic->i_pts = pts;
+	pic->i_type = force_key_frame ? X264_TYPE_IDR : X264_TYPE_AUTO;
+	int frame_size = x264_encoder_encode(h, &nal, &i_nal, pic, &pic_out);
+	pic->img.plane[0] = pic->img.plane[1] = pic->img.plane[2] = NULL;
+	if (frame_size > 0) {
+		*payload = nal[0].p_payload;
+	}
+	return frame_size;
+}
+*/
+import "C"
+
 import (
 	"context"
+	"errors"
+	"fmt"
 	"image"
+	"sync"
+	"time"
+	"unsafe"
 
 	"github.com/edaniels/golog"
-	"github.com/pion/mediadevices/pkg/codec"
-	"github.com/pion/mediadevices/pkg/codec/x264"
-	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/io/video"
 
 	ourcodec "github.com/edaniels/gostream/codec"
 )
 
 type encoder struct {
-	codec  codec.ReadCloser
-	img    image.Image
-	logger golog.Logger
+	mu            sync.Mutex
+	h             *C.x264_t
+	pic           *C.x264_picture_t
+	width         int
+	height        int
+	reader        video.Reader
+	rateControl   ourcodec.RateControlMode
+	maxBitrate    int
+	keyFrameForce bool
+	start         time.Time
+	lastPTS       int64
+	img           image.Image
+	logger        golog.Logger
 }
 
-// Gives suitable results. Probably want to make this configurable this in the future.
-const bitrate = 3_200_000
+// DefaultBitrate gives suitable results when no target bitrate is configured.
+const DefaultBitrate = 3_200_000
+
+const (
+	defaultPreset  = "ultrafast"
+	defaultProfile = "high"
+)
+
+var errEncoderClosed = errors.New("encoder closed")
+
+// presets is the set of x264 speed presets.
+var presets = map[string]bool{
+	"ultrafast": true,
+	"superfast": true,
+	"veryfast":  true,
+	"faster":    true,
+	"fast":      true,
+	"medium":    true,
+	"slow":      true,
+	"slower":    true,
+	"veryslow":  true,
+	"placebo":   true,
+}
+
+// levels maps H264 level names to the level_idc x264 expects. Level 1b is 9 by x264's
+// convention.
+var levels = map[string]int{
+	"1": 10, "1b": 9, "1.1": 11, "1.2": 12, "1.3": 13,
+	"2": 20, "2.1": 21, "2.2": 22,
+	"3": 30, "3.1": 31, "3.2": 32,
+	"4": 40, "4.1": 41, "4.2": 42,
+	"5": 50, "5.1": 51, "5.2": 52,
+	"6": 60, "6.1": 61, "6.2": 62,
+}
 
 // NewEncoder returns an x264 encoder that can encode images of the given width and height. It will
-// also ensure that it produces key frames at the given interval.
-func NewEncoder(width, height, keyFrameInterval int, logger golog.Logger) (ourcodec.VideoEncoder, error) {
-	enc := &encoder{logger: logger}
+// also ensure that it produces key frames at the given interval. Every encoder option but
+// ScalabilityMode is supported:
+//   - RateControl defaults to average bitrate (VBR). CBR fixes the bitrate at the target and
+//     cannot be combined with a different MaxBitrate. CQ encodes at constant quality capped at
+//     MaxBitrate, or the target bitrate if it is not set.
+//   - Profile is one of x264's profiles (e.g. "baseline", "main" or "high") and defaults to "high".
+//   - Level is the H264 level (e.g. "3.1") and is otherwise chosen by x264.
+//   - SpeedPreset is one of x264's presets (e.g. "ultrafast" or "medium") and defaults to "ultrafast".
+//
+// The bitrate can be changed while encoding without a key frame.
+func NewEncoder(
+	width, height, keyFrameInterval int,
+	opts ourcodec.VideoEncoderOptions,
+	logger golog.Logger,
+) (ourcodec.VideoEncoder, error) {
+	if opts.ScalabilityMode != "" {
+		return nil, fmt.Errorf("unsupported x264 encoder option: scalability mode %q", opts.ScalabilityMode)
+	}
+	preset := defaultPreset
+	if opts.SpeedPreset != "" {
+		if !presets[opts.SpeedPreset] {
+			return nil, fmt.Errorf("unknown x264 speed preset %q", opts.SpeedPreset)
+		}
+		preset = opts.SpeedPreset
+	}
+	profile := defaultProfile
+	if opts.Profile != "" {
+		profile = opts.Profile
+	}
+	var levelIdc int
+	if opts.Level != "" {
+		var ok bool
+		if levelIdc, ok = levels[opts.Level]; !ok {
+			return nil, fmt.Errorf("unknown x264 level %q", opts.Level)
+		}
+	}
+	targetBitrate := DefaultBitrate
+	if opts.TargetBitrate != 0 {
+		targetBitrate = opts.TargetBitrate
+	}
 
-	var builder codec.VideoEncoderBuilder
-	params, err := x264.NewParams()
-	if err != nil {
+	// x264 copies the parameters when the encoder is opened.
+	param := (*C.x264_param_t)(C.calloc(1, C.sizeof_x264_param_t))
+	defer C.free(unsafe.Pointer(param))
+	if err := configureParams(param, preset, width, height, keyFrameInterval, levelIdc); err != nil {
 		return nil, err
 	}
-	builder = &params
-	params.BitRate = bitrate
-	params.KeyFrameInterval = keyFrameInterval
-
-	codec, err := builder.BuildVideoEncoder(enc, prop.Media{
-		Video: prop.Video{
-			Width:  width,
-			Height: height,
-		},
-	})
-	if err != nil {
+	if err := configureBitrate(param, opts.RateControl, targetBitrate, opts.MaxBitrate); err != nil {
 		return nil, err
 	}
-	enc.codec = codec
+	// the profile is applied last since it checks the other parameters against it.
+	cProfile := C.CString(profile)
+	defer C.free(unsafe.Pointer(cProfile))
+	if C.x264_param_apply_profile(param, cProfile) < 0 {
+		return nil, fmt.Errorf("unsupported x264 profile %q", profile)
+	}
 
+	h := C.encoder_open(param)
+	if h == nil {
+		return nil, errors.New("x264_encoder_open failed")
+	}
+
+	// only the picture's parameters are kept; its planes are set on every encode.
+	pic := (*C.x264_picture_t)(C.calloc(1, C.sizeof_x264_picture_t))
+	C.x264_picture_init(pic)
+	pic.img.i_csp = C.X264_CSP_I420
+	pic.img.i_plane = 3
+
+	enc := &encoder{
+		h:           h,
+		pic:         pic,
+		width:       width,
+		height:      height,
+		rateControl: opts.RateControl,
+		maxBitrate:  opts.MaxBitrate,
+		logger:      logger,
+	}
+	enc.reader = video.ToI420(enc)
 	return enc, nil
 }
 
-// Read returns an image for codec to process.
+// configureParams sets up the given x264 parameters for low latency streaming.
+func configureParams(param *C.x264_param_t, preset string, width, height, keyFrameInterval, levelIdc int) error {
+	cPreset := C.CString(preset)
+	defer C.free(unsafe.Pointer(cPreset))
+	cTune := C.CString("zerolatency")
+	defer C.free(unsafe.Pointer(cTune))
+	if C.x264_param_default_preset(param, cPreset, cTune) < 0 {
+		return fmt.Errorf("x264_param_default_preset failed for preset %q", preset)
+	}
+	param.i_csp = C.X264_CSP_I420
+	param.i_width = C.int(width)
+	param.i_height = C.int(height)
+	param.i_keyint_max = C.int(keyFrameInterval)
+	if levelIdc != 0 {
+		param.i_level_idc = C.int(levelIdc)
+	}
+	// frames are timestamped with when they are encoded in milliseconds.
+	param.b_vfr_input = 1
+	param.i_timebase_num = 1
+	param.i_timebase_den = 1000
+	// every key frame carries the parameter sets so that viewers can join at any of them.
+	param.b_repeat_headers = 1
+	param.b_annexb = 1
+	return nil
+}
+
+// configureBitrate sets the rate control of the given x264 parameters for the given target and
+// max bitrate in bits per second. The VBV always caps the bitrate, which also lets x264 change
+// the bitrate while encoding. A max bitrate of zero caps it at the target bitrate.
+func configureBitrate(param *C.x264_param_t, rateControl ourcodec.RateControlMode, targetBitrate, maxBitrate int) error {
+	if maxBitrate != 0 && maxBitrate < targetBitrate {
+		return fmt.Errorf("max bitrate %d is less than target bitrate %d", maxBitrate, targetBitrate)
+	}
+	capBitrate := targetBitrate
+	if maxBitrate != 0 {
+		capBitrate = maxBitrate
+	}
+	// the buffer allows two seconds of variation around the cap.
+	bufferSize := 2 * capBitrate
+	switch rateControl {
+	case ourcodec.RateControlDefault, ourcodec.RateControlVBR:
+		param.rc.i_rc_method = C.X264_RC_ABR
+	case ourcodec.RateControlCBR:
+		if maxBitrate != 0 && maxBitrate != targetBitrate {
+			return fmt.Errorf("max bitrate %d must equal target bitrate %d for constant bitrate", maxBitrate, targetBitrate)
+		}
+		param.rc.i_rc_method = C.X264_RC_ABR
+		param.rc.b_filler = 1
+		bufferSize = capBitrate
+	case ourcodec.RateControlCQ:
+		param.rc.i_rc_method = C.X264_RC_CRF
+	default:
+		return fmt.Errorf("unknown x264 rate control mode %q", rateControl)
+	}
+	// x264 counts in kilobits.
+	param.rc.i_bitrate = C.int(targetBitrate / 1000)
+	param.rc.i_vbv_max_bitrate = C.int(capBitrate / 1000)
+	param.rc.i_vbv_buffer_size = C.int(bufferSize / 1000)
+	return nil
+}
+
+// SetBitrate changes the target bitrate of the encoder starting with the next encoded frame.
+// The encoder is reconfigured in place so no key frame is forced. A configured max bitrate
+// is kept as long as it is not below the new target.
+func (v *encoder) SetBitrate(bitrate int) error {
+	if bitrate <= 0 {
+		return fmt.Errorf("invalid bitrate %d", bitrate)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.h == nil {
+		return errEncoderClosed
+	}
+
+	maxBitrate := v.maxBitrate
+	if v.rateControl == ourcodec.RateControlCBR {
+		maxBitrate = 0
+	}
+	param := (*C.x264_param_t)(C.calloc(1, C.sizeof_x264_param_t))
+	defer C.free(unsafe.Pointer(param))
+	C.x264_encoder_parameters(v.h, param)
+	if err := configureBitrate(param, v.rateControl, bitrate, maxBitrate); err != nil {
+		return err
+	}
+	if ret := C.x264_encoder_reconfig(v.h, param); ret < 0 {
+		return fmt.Errorf("x264_encoder_reconfig failed (%d)", ret)
+	}
+	return nil
+}
+
+// ForceKeyFrame forces the next encoded frame to be a key frame.
+func (v *encoder) ForceKeyFrame() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.h == nil {
+		return errEncoderClosed
+	}
+	v.keyFrameForce = true
+	return nil
+}
+
+// Read returns an image for the I420 converter to process.
 func (v *encoder) Read() (img image.Image, release func(), err error) {
-	return v.img, nil, nil
+	return v.img, func() {}, nil
 }
 
-// Encode asks the codec to process the given image.
+// Encode encodes the given image, returning nothing if x264 has no frame to output yet.
 func (v *encoder) Encode(_ context.Context, img image.Image) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.h == nil {
+		return nil, errEncoderClosed
+	}
+
 	v.img = img
-	data, release, err := v.codec.Read()
-	dataCopy := make([]byte, len(data))
-	copy(dataCopy, data)
-	release()
-	return dataCopy, err
+	converted, release, err := v.reader.Read()
+	v.img = nil
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	yuvImg := converted.(*image.YCbCr)
+	if bounds := yuvImg.Bounds(); bounds.Dx() != v.width || bounds.Dy() != v.height {
+		return nil, fmt.Errorf("expected image of %dx%d but got %dx%d", v.width, v.height, bounds.Dx(), bounds.Dy())
+	}
+
+	now := time.Now()
+	pts := now.Sub(v.start).Milliseconds()
+	if v.start.IsZero() {
+		v.start = now
+		pts = 0
+	} else if pts <= v.lastPTS {
+		// x264 expects every frame to have a later timestamp than the one before.
+		pts = v.lastPTS + 1
+	}
+	var forceKeyFrame C.int
+	if v.keyFrameForce {
+		forceKeyFrame = 1
+	}
+	var payload *C.uint8_t
+	frameSize := C.encoder_encode(
+		v.h, v.pic,
+		(*C.uint8_t)(&yuvImg.Y[0]), (*C.uint8_t)(&yuvImg.Cb[0]), (*C.uint8_t)(&yuvImg.Cr[0]),
+		C.int(yuvImg.YStride), C.int(yuvImg.CStride),
+		C.int64_t(pts), forceKeyFrame, &payload,
+	)
+	if frameSize < 0 {
+		return nil, fmt.Errorf("x264_encoder_encode failed (%d)", frameSize)
+	}
+	v.keyFrameForce = false
+	v.lastPTS = pts
+	if frameSize == 0 {
+		return nil, nil
+	}
+	return C.GoBytes(unsafe.Pointer(payload), frameSize), nil
+}
+
+// Close releases the libx264 encoder.
+func (v *encoder) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.h == nil {
+		return
+	}
+	C.x264_encoder_close(v.h)
+	C.free(unsafe.Pointer(v.pic))
+	v.h = nil
 }
@@ -13,6 +13,8 @@ import (
 	"github.com/edaniels/golog"
 	"github.com/nfnt/resize"
 	"go.viam.com/test"
+
+	ourcodec "github.com/edaniels/gostream/codec"
 )
 
 const (
@@ -59,8 +61,9 @@ func BenchmarkEncodeRGBA(b *testing.B) {
 	imgCyan := getResizedImageFromFile(b, "../../data/cyan.png")
 	imgFuchsia := getResizedImageFromFile(b, "../../data/fuchsia.png")
 	ctx := context.Background()
-	encoder, err := NewEncoder(Width, Height, DefaultKeyFrameInterval, logger)
+	encoder, err := NewEncoder(Width, Height, DefaultKeyFrameInterval, ourcodec.VideoEncoderOptions{}, logger)
 	test.That(b, err, test.ShouldBeNil)
+	defer encoder.Close()
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -87,8 +90,9 @@ func BenchmarkEncodeYCbCr(b *testing.B) {
 	imgCY, err := convertToYCbCr(b, imgCyan)
 	test.That(b, err, test.ShouldBeNil)
 
-	encoder, err := NewEncoder(Width, Height, DefaultKeyFrameInterval, logger)
+	encoder, err := NewEncoder(Width, Height, DefaultKeyFrameInterval, ourcodec.VideoEncoderOptions{}, logger)
 	test.That(b, err, test.ShouldBeNil)
+	defer encoder.Close()
 
 	ctx := context.Background()
 	b.ResetTimer()
@@ -103,3 +107,91 @@ func BenchmarkEncodeYCbCr(b *testing.B) {
 		w = !w
 	}
 }
+
+func gradientImage(width, height, offset int) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio420)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Y[y*img.YStride+x] = uint8(16 + (x*4+offset)%200)
+		}
+	}
+	for i := range img.Cb {
+		img.Cb[i] = 128
+		img.Cr[i] = 128
+	}
+	return img
+}
+
+// isIDR returns whether or not the given Annex B access unit contains an IDR slice.
+func isIDR(frame []byte) bool {
+	for i := 0; i+3 < len(frame); i++ {
+		if frame[i] == 0 && frame[i+1] == 0 && frame[i+2] == 1 && frame[i+3]&0x1f == 5 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEncoderOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts ourcodec.VideoEncoderOptions
+		ok   bool
+	}{
+		{"defaults", ourcodec.VideoEncoderOptions{}, true},
+		{"vbr with max", ourcodec.VideoEncoderOptions{TargetBitrate: 500_000, MaxBitrate: 1_000_000, RateControl: ourcodec.RateControlVBR}, true},
+		{"cbr", ourcodec.VideoEncoderOptions{TargetBitrate: 500_000, RateControl: ourcodec.RateControlCBR}, true},
+		{
+			"cbr with max",
+			ourcodec.VideoEncoderOptions{TargetBitrate: 500_000, MaxBitrate: 1_000_000, RateControl: ourcodec.RateControlCBR},
+			false,
+		},
+		{"cq", ourcodec.VideoEncoderOptions{MaxBitrate: 4_000_000, RateControl: ourcodec.RateControlCQ}, true},
+		{"unknown rate control", ourcodec.VideoEncoderOptions{RateControl: "abr"}, false},
+		{"max below target", ourcodec.VideoEncoderOptions{TargetBitrate: 500_000, MaxBitrate: 400_000}, false},
+		{"profile and level", ourcodec.VideoEncoderOptions{Profile: "baseline", Level: "3.1"}, true},
+		{"unknown profile", ourcodec.VideoEncoderOptions{Profile: "extended-plus"}, false},
+		{"unknown level", ourcodec.VideoEncoderOptions{Level: "3.7"}, false},
+		{"speed preset", ourcodec.VideoEncoderOptions{SpeedPreset: "veryfast"}, true},
+		{"unknown speed preset", ourcodec.VideoEncoderOptions{SpeedPreset: "warp"}, false},
+		{"scalability mode", ourcodec.VideoEncoderOptions{ScalabilityMode: "L1T2"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			enc, err := NewEncoder(64, 48, DefaultKeyFrameInterval, tc.opts, golog.NewTestLogger(t))
+			if !tc.ok {
+				test.That(t, err, test.ShouldNotBeNil)
+				return
+			}
+			test.That(t, err, test.ShouldBeNil)
+			defer enc.Close()
+			encoded, err := enc.Encode(context.Background(), gradientImage(64, 48, 0))
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, isIDR(encoded), test.ShouldBeTrue)
+		})
+	}
+}
+
+func TestSetBitrate(t *testing.T) {
+	enc, err := NewEncoder(64, 48, DefaultKeyFrameInterval, ourcodec.VideoEncoderOptions{}, golog.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer enc.Close()
+
+	encoded, err := enc.Encode(context.Background(), gradientImage(64, 48, 0))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, isIDR(encoded), test.ShouldBeTrue)
+
+	// changing the bitrate does not cost a key frame.
+	test.That(t, enc.(ourcodec.BitrateController).SetBitrate(500_000), test.ShouldBeNil)
+	encoded, err = enc.Encode(context.Background(), gradientImage(64, 48, 1))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, encoded, test.ShouldNotBeEmpty)
+	test.That(t, isIDR(encoded), test.ShouldBeFalse)
+
+	test.That(t, enc.(ourcodec.BitrateController).SetBitrate(0), test.ShouldNotBeNil)
+
+	// a key frame can still be forced.
+	test.That(t, enc.(ourcodec.KeyFrameController).ForceKeyFrame(), test.ShouldBeNil)
+	encoded, err = enc.Encode(context.Background(), gradientImage(64, 48, 2))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, isIDR(encoded), test.ShouldBeTrue)
+}
@@ -0,0 +1,197 @@
+package gostream
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// CongestionControlConfig configures how a stream adapts its video to the bandwidth available
+// to its viewers. The bandwidth is estimated from the RTCP feedback viewers send: the receiver
+// estimated maximum bitrate (REMB) caps the estimate and the packet loss in receiver reports
+// raises or lowers it.
+//
+// Delay based estimation with pion's Google congestion control (GCC) interceptor is not done.
+// The interceptor must be registered with the webrtc.API that peer connections are made with,
+// and go.viam.com/utils/rpc, which makes the peer connections of a StreamServer, builds that
+// API itself with pion's default interceptors alone and offers no way of adding to them. Its
+// peer connections do negotiate transport-wide congestion control feedback, but GCC needs the
+// send time of every packet of the connection, including those of other streams and those
+// retransmitted by pion, which only an interceptor sees.
+//
+// All viewers of a simulcast layer share its encoders so each layer follows the estimate of the
+// viewer with the least bandwidth among those it is sent to. Layers are estimated and adapted
+// separately so that viewers of one layer do not degrade the others.
+type CongestionControlConfig struct {
+	// MinBitrate and MaxBitrate bound the estimated bitrate in bits per second. Zero uses
+	// DefaultMinVideoBitrate and the target bitrate of the stream's VideoEncoderOptions or,
+	// if that is not set either, DefaultMaxVideoBitrate. A simulcast layer with a target
+	// bitrate of its own is bounded by that instead if it is lower.
+	MinBitrate int
+	MaxBitrate int
+
+	// AdaptResolution halves the resolution of the video each time the estimate falls below a
+	// fraction of MaxBitrate, for up to a quarter of the resolution.
+	AdaptResolution bool
+
+	// AdaptFrameRate halves the frame rate of the video the same way.
+	AdaptFrameRate bool
+}
+
+const (
+	// DefaultMinVideoBitrate is the lowest bitrate congestion control lowers video to by default.
+	DefaultMinVideoBitrate = 100_000
+	// DefaultMaxVideoBitrate is the default bitrate of the bundled video encoders.
+	DefaultMaxVideoBitrate = 3_200_000
+)
+
+// The loss based estimate follows the loss based controller of Google congestion control:
+// it grows while there is little loss, holds steady through moderate loss and shrinks with
+// heavy loss.
+const (
+	lowPacketLoss       = 0.02
+	highPacketLoss      = 0.1
+	lossBitrateIncrease = 1.08
+	// minBitrateChange is how much the estimate must change by before encoders are updated.
+	minBitrateChange = 0.1
+	// bitrateIncreaseInterval is how long after an update the estimate may be raised again so
+	// that encoders are not reconfigured with every report. A lower estimate is applied at once
+	// since the viewers are already congested.
+	bitrateIncreaseInterval = time.Second
+)
+
+// congestionLevelThresholds are the fractions of the maximum bitrate below which the video is
+// degraded by one more level. A level is left again once the estimate is above its threshold
+// by congestionLevelHysteresis so that the video does not flip back and forth.
+var congestionLevelThresholds = []float64{0.3, 0.1}
+
+const congestionLevelHysteresis = 2
+
+// congestionController estimates the bitrate the viewers of a video layer can receive.
+type congestionController struct {
+	mu         sync.Mutex
+	minBitrate float64
+	maxBitrate float64
+	viewers    map[webrtc.SSRC]*viewerEstimate
+	// estimate and level are what was last applied to the layer, at lastUpdate.
+	estimate   float64
+	level      int
+	lastUpdate time.Time
+}
+
+// viewerEstimate is the bitrate estimated for the video sent on a single SSRC.
+type viewerEstimate struct {
+	lossBased float64
+	// remb is the latest REMB for the SSRC, or zero if there was none.
+	remb float64
+}
+
+func newCongestionController(config CongestionControlConfig, targetBitrate int) *congestionController {
+	minBitrate, maxBitrate := config.MinBitrate, config.MaxBitrate
+	if minBitrate == 0 {
+		minBitrate = DefaultMinVideoBitrate
+	}
+	if maxBitrate == 0 {
+		maxBitrate = targetBitrate
+	}
+	if maxBitrate == 0 {
+		maxBitrate = DefaultMaxVideoBitrate
+	}
+	if minBitrate > maxBitrate {
+		minBitrate = maxBitrate
+	}
+	return &congestionController{
+		minBitrate: float64(minBitrate),
+		maxBitrate: float64(maxBitrate),
+		viewers:    map[webrtc.SSRC]*viewerEstimate{},
+		estimate:   float64(maxBitrate),
+	}
+}
+
+// handle updates the estimates of viewers with the given feedback. Only SSRCs for which
+// isVideo returns true are considered and viewers that are no longer sent video are forgotten.
+// It returns the layer's new estimate and degradation level if either changed enough to
+// be applied.
+func (c *congestionController) handle(event RTCPEvent, isVideo func(ssrc webrtc.SSRC) bool) (int, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch event := event.(type) {
+	case ReceiverReportEvent:
+		if !isVideo(event.SSRC) {
+			return 0, 0, false
+		}
+		viewer := c.viewer(event.SSRC)
+		switch {
+		case event.FractionLost < lowPacketLoss:
+			viewer.lossBased *= lossBitrateIncrease
+		case event.FractionLost > highPacketLoss:
+			viewer.lossBased *= 1 - event.FractionLost/2
+		}
+		viewer.lossBased = math.Max(c.minBitrate, math.Min(c.maxBitrate, viewer.lossBased))
+	case REMBEvent:
+		var found bool
+		for _, ssrc := range event.SSRCs {
+			if isVideo(ssrc) {
+				c.viewer(ssrc).remb = event.Bitrate
+				found = true
+			}
+		}
+		if !found {
+			return 0, 0, false
+		}
+	default:
+		return 0, 0, false
+	}
+
+	estimate := c.maxBitrate
+	for ssrc, viewer := range c.viewers {
+		if !isVideo(ssrc) {
+			delete(c.viewers, ssrc)
+			continue
+		}
+		viewerBitrate := viewer.lossBased
+		if viewer.remb != 0 {
+			viewerBitrate = math.Min(viewerBitrate, viewer.remb)
+		}
+		estimate = math.Min(estimate, viewerBitrate)
+	}
+	estimate = math.Max(c.minBitrate, estimate)
+
+	level := c.levelFor(estimate)
+	if level == c.level && math.Abs(estimate-c.estimate) < c.estimate*minBitrateChange {
+		return 0, 0, false
+	}
+	if estimate > c.estimate && time.Since(c.lastUpdate) < bitrateIncreaseInterval {
+		return 0, 0, false
+	}
+	c.estimate = estimate
+	c.level = level
+	c.lastUpdate = time.Now()
+	return int(estimate), level, true
+}
+
+// viewer returns the estimate of the given SSRC, starting at the maximum bitrate.
+func (c *congestionController) viewer(ssrc webrtc.SSRC) *viewerEstimate {
+	viewer, ok := c.viewers[ssrc]
+	if !ok {
+		viewer = &viewerEstimate{lossBased: c.maxBitrate}
+		c.viewers[ssrc] = viewer
+	}
+	return viewer
+}
+
+// levelFor returns the degradation level for the given estimate given the current level.
+func (c *congestionController) levelFor(estimate float64) int {
+	ratio := estimate / c.maxBitrate
+	level := c.level
+	for level < len(congestionLevelThresholds) && ratio < congestionLevelThresholds[level] {
+		level++
+	}
+	for level > 0 && ratio > congestionLevelThresholds[level-1]*congestionLevelHysteresis {
+		level--
+	}
+	return level
+}
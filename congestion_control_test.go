@@ -0,0 +1,114 @@
+package gostream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+
+	"github.com/edaniels/gostream/codec"
+)
+
+func TestCongestionController(t *testing.T) {
+	video := map[webrtc.SSRC]bool{1: true, 2: true}
+	isVideo := func(ssrc webrtc.SSRC) bool { return video[ssrc] }
+
+	c := newCongestionController(CongestionControlConfig{MinBitrate: 100_000}, 1_000_000)
+
+	// audio feedback is ignored.
+	_, _, changed := c.handle(ReceiverReportEvent{SSRC: 3, FractionLost: 0.5}, isVideo)
+	test.That(t, changed, test.ShouldBeFalse)
+
+	// low loss cannot raise the estimate above the maximum.
+	_, _, changed = c.handle(ReceiverReportEvent{SSRC: 1}, isVideo)
+	test.That(t, changed, test.ShouldBeFalse)
+
+	// heavy loss lowers the estimate.
+	bitrate, level, changed := c.handle(ReceiverReportEvent{SSRC: 1, FractionLost: 0.4}, isVideo)
+	test.That(t, changed, test.ShouldBeTrue)
+	test.That(t, bitrate, test.ShouldEqual, 800_000)
+	test.That(t, level, test.ShouldEqual, 0)
+
+	// the stream follows the viewer with the least bandwidth.
+	bitrate, level, changed = c.handle(REMBEvent{Bitrate: 200_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeTrue)
+	test.That(t, bitrate, test.ShouldEqual, 200_000)
+	test.That(t, level, test.ShouldEqual, 1)
+
+	// the estimate does not go below the minimum.
+	bitrate, level, changed = c.handle(REMBEvent{Bitrate: 10_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeTrue)
+	test.That(t, bitrate, test.ShouldEqual, 100_000)
+	test.That(t, level, test.ShouldEqual, 1)
+
+	_, _, changed = c.handle(REMBEvent{Bitrate: 50_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeFalse)
+
+	// small changes are not applied.
+	_, _, changed = c.handle(REMBEvent{Bitrate: 105_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeFalse)
+
+	// the estimate is raised at most once per interval.
+	_, _, changed = c.handle(REMBEvent{Bitrate: 350_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeFalse)
+	c.lastUpdate = time.Now().Add(-bitrateIncreaseInterval)
+	bitrate, level, changed = c.handle(REMBEvent{Bitrate: 350_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeTrue)
+	test.That(t, bitrate, test.ShouldEqual, 350_000)
+	test.That(t, level, test.ShouldEqual, 1)
+
+	// a level is only left with some headroom.
+	c.lastUpdate = time.Now().Add(-bitrateIncreaseInterval)
+	_, level, _ = c.handle(REMBEvent{Bitrate: 500_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, level, test.ShouldEqual, 1)
+	c.lastUpdate = time.Now().Add(-bitrateIncreaseInterval)
+	bitrate, level, changed = c.handle(REMBEvent{Bitrate: 700_000, SSRCs: []webrtc.SSRC{2}}, isVideo)
+	test.That(t, changed, test.ShouldBeTrue)
+	test.That(t, bitrate, test.ShouldEqual, 700_000)
+	test.That(t, level, test.ShouldEqual, 0)
+
+	// viewers no longer sent video are forgotten.
+	delete(video, 2)
+	c.lastUpdate = time.Now().Add(-bitrateIncreaseInterval)
+	bitrate, _, changed = c.handle(ReceiverReportEvent{SSRC: 1}, isVideo)
+	test.That(t, changed, test.ShouldBeTrue)
+	test.That(t, bitrate, test.ShouldEqual, 864_000)
+}
+
+func TestCongestionControlPerLayer(t *testing.T) {
+	stream, err := NewStream(StreamConfig{
+		VideoEncoderFactory: &slowVideoEncoderFactory{},
+		VideoEncoderOptions: codec.VideoEncoderOptions{TargetBitrate: 1_000_000},
+		Simulcast:           []SimulcastLayer{{RID: "f"}, {RID: "q", ScaleResolutionDownBy: 4, TargetBitrate: 300_000}},
+		CongestionControl:   &CongestionControlConfig{AdaptResolution: true},
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+	bs := stream.(*basicStream)
+	full, quarter := bs.videoLayers[0], bs.videoLayers[1]
+	full.track.rtpTrack.bindings = []trackBinding{{ssrc: 1, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1}}
+	quarter.track.rtpTrack.bindings = []trackBinding{{ssrc: 2, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1}}
+
+	// a congested viewer of one layer leaves the other layer alone.
+	stream.handleRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 150_000, SSRCs: []uint32{2}}})
+	test.That(t, full.bitrateLimit, test.ShouldEqual, 0)
+	test.That(t, quarter.bitrateLimit, test.ShouldEqual, 150_000)
+	resolution, _ := bs.congestionScale(full)
+	test.That(t, resolution, test.ShouldEqual, 1)
+	resolution, _ = bs.congestionScale(quarter)
+	test.That(t, resolution, test.ShouldEqual, 1)
+
+	stream.handleRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 200_000, SSRCs: []uint32{1}}})
+	test.That(t, full.bitrateLimit, test.ShouldEqual, 200_000)
+	test.That(t, quarter.bitrateLimit, test.ShouldEqual, 150_000)
+	resolution, _ = bs.congestionScale(full)
+	test.That(t, resolution, test.ShouldEqual, 2)
+
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	test.That(t, bs.layerBitrate(full), test.ShouldEqual, 200_000)
+	test.That(t, bs.layerBitrate(quarter), test.ShouldEqual, 150_000)
+}
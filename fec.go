@@ -0,0 +1,164 @@
+package gostream
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// Forward error correction is sent as ULPFEC (RFC 5109) carried in RED (RFC 2198) on the
+// video's own SSRC, which is how browsers expect it. FlexFEC is not supported since it is
+// sent on an SSRC of its own which pion cannot signal for outbound tracks.
+const (
+	mimeTypeRED    = "video/red"
+	mimeTypeULPFEC = "video/ulpfec"
+)
+
+// RegisterFECCodecs registers the codecs needed to negotiate forward error correction of
+// video with the given MediaEngine. Peers only receive FEC if the media engines of both
+// sides of their connection have these codecs. The peer connections of a StreamServer are
+// made by go.viam.com/utils/rpc with its default codecs, so their viewers are sent no FEC.
+func RegisterFECCodecs(m *webrtc.MediaEngine) error {
+	for _, codec := range []webrtc.RTPCodecParameters{
+		{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypeRED, ClockRate: 90000},
+			PayloadType:        116,
+		},
+		{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypeULPFEC, ClockRate: 90000},
+			PayloadType:        118,
+		},
+	} {
+		if err := m.RegisterCodec(codec, webrtc.RTPCodecTypeVideo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	// rtpHeaderSize is the size of an RTP header without CSRCs or extensions. ULPFEC protects
+	// everything after it.
+	rtpHeaderSize    = 12
+	ulpfecHeaderSize = 10
+	// a level 0 header has a 16 bit mask, or a 48 bit one when its long mask flag is set.
+	ulpfecShortLevelHeaderSize = 4
+	ulpfecLongLevelHeaderSize  = 8
+	ulpfecShortMaskPackets     = 16
+	ulpfecLongMaskPackets      = 48
+)
+
+// fecEncoder protects the packets sent to a single binding with ULPFEC. Every media packet
+// is wrapped in RED and after every group of media packets one FEC packet is sent that
+// recovers any single packet of the group lost.
+type fecEncoder struct {
+	redPayloadType    uint8
+	ulpfecPayloadType uint8
+	groupSize         int
+	// sequenceOffset counts the FEC packets sent since they take up sequence numbers in
+	// between those of the media.
+	sequenceOffset uint16
+	// group holds the marshaled media packets not yet protected.
+	group [][]byte
+}
+
+// newFECEncoder returns an fecEncoder that sends at least the given fraction of FEC packets
+// per media packet, with the given negotiated payload types.
+func newFECEncoder(redPayloadType, ulpfecPayloadType webrtc.PayloadType, overhead float64) *fecEncoder {
+	groupSize := int(math.Max(1, math.Min(ulpfecLongMaskPackets, math.Floor(1/overhead))))
+	return &fecEncoder{
+		redPayloadType:    uint8(redPayloadType),
+		ulpfecPayloadType: uint8(ulpfecPayloadType),
+		groupSize:         groupSize,
+	}
+}
+
+// protect returns the packets to send for the given media packet: the media wrapped in RED,
+// followed by an FEC packet if it completes a group.
+func (e *fecEncoder) protect(header *rtp.Header, payload []byte) ([]rtp.Packet, error) {
+	media := *header
+	media.SequenceNumber += e.sequenceOffset
+	raw, err := (&rtp.Packet{Header: media, Payload: payload}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	// the mask cannot reach packets too far apart, which only happens when relayed packets
+	// have gaps, so those are left unprotected.
+	if len(e.group) > 0 && media.SequenceNumber-binary.BigEndian.Uint16(e.group[0][2:4]) >= ulpfecLongMaskPackets {
+		e.group = e.group[:0]
+	}
+	e.group = append(e.group, raw)
+
+	red := media
+	red.PayloadType = e.redPayloadType
+	packets := []rtp.Packet{{Header: red, Payload: append([]byte{media.PayloadType & 0x7f}, payload...)}}
+	if len(e.group) < e.groupSize {
+		return packets, nil
+	}
+
+	fec := red
+	fec.SequenceNumber++
+	fec.Marker = false
+	fec.CSRC = nil
+	fec.Extension = false
+	fec.Extensions = nil
+	fec.Padding = false
+	packets = append(packets, rtp.Packet{Header: fec, Payload: append([]byte{e.ulpfecPayloadType}, e.fecPayload()...)})
+	e.sequenceOffset++
+	e.group = e.group[:0]
+	return packets, nil
+}
+
+// fecPayload returns the ULPFEC payload protecting the packets of the current group with a
+// single level of protection.
+func (e *fecEncoder) fecPayload() []byte {
+	base := binary.BigEndian.Uint16(e.group[0][2:4])
+	var protectionLength int
+	longMask := false
+	for _, raw := range e.group {
+		if len(raw)-rtpHeaderSize > protectionLength {
+			protectionLength = len(raw) - rtpHeaderSize
+		}
+		if binary.BigEndian.Uint16(raw[2:4])-base >= ulpfecShortMaskPackets {
+			longMask = true
+		}
+	}
+	levelHeaderSize := ulpfecShortLevelHeaderSize
+	if longMask {
+		levelHeaderSize = ulpfecLongLevelHeaderSize
+	}
+
+	payload := make([]byte, ulpfecHeaderSize+levelHeaderSize+protectionLength)
+	var lengthRecovery uint16
+	var mask uint64
+	for _, raw := range e.group {
+		// the first two bytes recover the padding, extension, CSRC count, marker and payload
+		// type, but not the version.
+		payload[0] ^= raw[0] & 0x3f
+		payload[1] ^= raw[1]
+		for i := 0; i < 4; i++ {
+			payload[4+i] ^= raw[4+i]
+		}
+		lengthRecovery ^= uint16(len(raw) - rtpHeaderSize)
+		mask |= 1 << (ulpfecLongMaskPackets - 1 - uint64(binary.BigEndian.Uint16(raw[2:4])-base))
+		protected := payload[ulpfecHeaderSize+levelHeaderSize:]
+		for i, b := range raw[rtpHeaderSize:] {
+			protected[i] ^= b
+		}
+	}
+	if longMask {
+		payload[0] |= 0x40
+	}
+	binary.BigEndian.PutUint16(payload[2:4], base)
+	binary.BigEndian.PutUint16(payload[8:10], lengthRecovery)
+
+	level := payload[ulpfecHeaderSize:]
+	binary.BigEndian.PutUint16(level[0:2], uint16(protectionLength))
+	binary.BigEndian.PutUint16(level[2:4], uint16(mask>>32))
+	if longMask {
+		binary.BigEndian.PutUint32(level[4:8], uint32(mask))
+	}
+	return payload
+}
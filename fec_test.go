@@ -0,0 +1,80 @@
+package gostream
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+	"go.viam.com/test"
+)
+
+func TestFECEncoder(t *testing.T) {
+	e := newFECEncoder(116, 118, 0.25)
+	test.That(t, e.groupSize, test.ShouldEqual, 4)
+
+	media := []rtp.Packet{
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 10, Timestamp: 1000, SSRC: 5}, Payload: []byte{1, 2, 3}},
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 11, Timestamp: 1000, SSRC: 5, Marker: true}, Payload: []byte{4, 5}},
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 12, Timestamp: 4000, SSRC: 5}, Payload: []byte{6, 7, 8, 9, 10}},
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 13, Timestamp: 4000, SSRC: 5, Marker: true}, Payload: []byte{11}},
+	}
+	var sent []rtp.Packet
+	for i := range media {
+		packets, err := e.protect(&media[i].Header, media[i].Payload)
+		test.That(t, err, test.ShouldBeNil)
+		sent = append(sent, packets...)
+	}
+	test.That(t, sent, test.ShouldHaveLength, 5)
+	for i, packet := range sent[:4] {
+		test.That(t, packet.PayloadType, test.ShouldEqual, 116)
+		test.That(t, packet.SequenceNumber, test.ShouldEqual, media[i].SequenceNumber)
+		test.That(t, packet.Marker, test.ShouldEqual, media[i].Marker)
+		test.That(t, packet.Payload, test.ShouldResemble, append([]byte{96}, media[i].Payload...))
+	}
+	fec := sent[4]
+	test.That(t, fec.PayloadType, test.ShouldEqual, 116)
+	test.That(t, fec.SequenceNumber, test.ShouldEqual, 14)
+	test.That(t, fec.Marker, test.ShouldBeFalse)
+	test.That(t, fec.Payload[0], test.ShouldEqual, 118)
+
+	// media following an FEC packet is shifted past its sequence number.
+	packets, err := e.protect(&rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 14, SSRC: 5}, []byte{12})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, packets, test.ShouldHaveLength, 1)
+	test.That(t, packets[0].SequenceNumber, test.ShouldEqual, 15)
+
+	// any single lost packet of the group can be recovered.
+	payload := fec.Payload[1:]
+	test.That(t, binary.BigEndian.Uint16(payload[2:4]), test.ShouldEqual, 10)
+	test.That(t, binary.BigEndian.Uint16(payload[12:14]), test.ShouldEqual, 0xf000)
+	for lost := range media {
+		recovered := make([]byte, rtpHeaderSize+int(binary.BigEndian.Uint16(payload[10:12])))
+		copy(recovered[0:2], payload[0:2])
+		copy(recovered[4:8], payload[4:8])
+		copy(recovered[rtpHeaderSize:], payload[14:])
+		length := binary.BigEndian.Uint16(payload[8:10])
+		for i := range media {
+			if i == lost {
+				continue
+			}
+			raw, err := media[i].Marshal()
+			test.That(t, err, test.ShouldBeNil)
+			recovered[0] ^= raw[0]
+			recovered[1] ^= raw[1]
+			for j := 4; j < 8; j++ {
+				recovered[j] ^= raw[j]
+			}
+			for j, b := range raw[rtpHeaderSize:] {
+				recovered[rtpHeaderSize+j] ^= b
+			}
+			length ^= uint16(len(raw) - rtpHeaderSize)
+		}
+		recovered[0] = 0x80 | recovered[0]&0x3f
+		binary.BigEndian.PutUint16(recovered[2:4], 10+uint16(lost))
+		binary.BigEndian.PutUint32(recovered[8:12], fec.SSRC)
+
+		expected, err := media[lost].Marshal()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, recovered[:rtpHeaderSize+int(length)], test.ShouldResemble, expected)
+	}
+}
@@ -0,0 +1,29 @@
+package gostream
+
+import (
+	"time"
+)
+
+// maxAudioClockDrift is how far the media time of audio chunks may drift from when they were
+// captured before it is set back to the time of capture.
+const maxAudioClockDrift = 100 * time.Millisecond
+
+// mediaClock is the clock shared by all tracks of a stream. Media is timestamped with the
+// time it was captured and every binding maps that time onto its RTP timestamps, so the
+// timestamps of all tracks advance with the same clock. Sender reports built from the clock,
+// see SendSenderReports, relate them all to the same NTP time.
+type mediaClock struct {
+	epoch time.Time
+}
+
+func newMediaClock() *mediaClock {
+	return &mediaClock{epoch: time.Now()}
+}
+
+// rtpTime returns the time elapsed on the clock at the given time in units of the given
+// clock rate. It wraps around like RTP timestamps do.
+func (c *mediaClock) rtpTime(t time.Time, clockRate uint32) uint32 {
+	elapsed := t.Sub(c.epoch)
+	seconds, fraction := int64(elapsed/time.Second), int64(elapsed%time.Second)
+	return uint32(seconds*int64(clockRate) + fraction*int64(clockRate)/int64(time.Second))
+}
@@ -0,0 +1,56 @@
+package gostream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+func TestMediaClock(t *testing.T) {
+	clock := newMediaClock()
+	test.That(t, clock.rtpTime(clock.epoch, 90000), test.ShouldEqual, 0)
+	test.That(t, clock.rtpTime(clock.epoch.Add(1500*time.Millisecond), 90000), test.ShouldEqual, 135000)
+	test.That(t, clock.rtpTime(clock.epoch.Add(20*time.Millisecond), 48000), test.ShouldEqual, 960)
+}
+
+func TestMediaTimestampedWhenCaptured(t *testing.T) {
+	clock := newMediaClock()
+	video := newVideoTrackLocalStaticSample(
+		[]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}}, "video", "", "stream", clock, 0, 0)
+	audio := newAudioTrackLocalStaticSample(
+		[]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000}}, "audio", "stream", clock)
+	audio.setAudioLatency(20 * time.Millisecond)
+
+	var videoWriter, audioWriter fakeTrackLocalWriter
+	_, err := video.Bind(&fakeTrackLocalContext{id: "video", ssrc: 1, writeStream: &videoWriter})
+	test.That(t, err, test.ShouldBeNil)
+	_, err = audio.Bind(&fakeTrackLocalContext{
+		id:          "audio",
+		ssrc:        2,
+		writeStream: &audioWriter,
+		codecs: []webrtc.RTPCodecParameters{{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000},
+			PayloadType:        111,
+		}},
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	// media captured a while before it is written keeps the time it was captured, on the same
+	// clock for both tracks.
+	capturedAt := time.Now().Add(-time.Second)
+	test.That(t, video.WriteData(0, []byte{1, 2, 3}, 0, capturedAt), test.ShouldBeNil)
+	test.That(t, audio.WriteData(0, []byte{1, 2, 3}, 0, capturedAt), test.ShouldBeNil)
+	test.That(t, videoWriter.headers, test.ShouldHaveLength, 1)
+	test.That(t, audioWriter.headers, test.ShouldHaveLength, 1)
+	test.That(t, videoWriter.headers[0].Timestamp, test.ShouldEqual,
+		video.rtpTrack.bindings[0].packetizer.timestamp(clock, capturedAt))
+	test.That(t, audioWriter.headers[0].Timestamp, test.ShouldEqual,
+		audio.rtpTrack.bindings[0].packetizer.timestamp(clock, capturedAt))
+
+	// audio that follows on closely continues from the previous chunk.
+	test.That(t, audio.WriteData(0, []byte{4, 5, 6}, 0, capturedAt.Add(25*time.Millisecond)), test.ShouldBeNil)
+	test.That(t, audioWriter.headers, test.ShouldHaveLength, 2)
+	test.That(t, audioWriter.headers[1].Timestamp-audioWriter.headers[0].Timestamp, test.ShouldEqual, 960)
+}
@@ -0,0 +1,100 @@
+package gostream
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// H264 and H265 NAL unit types of parameter sets and key frames.
+const (
+	h264NALUTypeIDR = 5
+	h264NALUTypeSPS = 7
+	h264NALUTypePPS = 8
+
+	h265NALUTypeFirstIRAP = 16
+	h265NALUTypeLastIRAP  = 23
+	h265NALUTypeVPS       = 32
+	h265NALUTypeSPS       = 33
+	h265NALUTypePPS       = 34
+)
+
+// parameterSets keeps the latest parameter sets of an H264 or H265 video so that they can
+// be sent ahead of a key frame to peers that have not received them yet. Encoders may only
+// output parameter sets at the start of the video, which peers joining later never see.
+type parameterSets struct {
+	h265 bool
+	// types are the NAL unit types of the parameter sets in the order they are sent.
+	types []byte
+	// latest holds the latest NAL unit of each parameter set type.
+	latest map[byte][]byte
+}
+
+// newParameterSets returns parameterSets for video of the given MIME type, or nil if the
+// codec has no parameter sets.
+func newParameterSets(mimeType string) *parameterSets {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return &parameterSets{
+			types:  []byte{h264NALUTypeSPS, h264NALUTypePPS},
+			latest: map[byte][]byte{},
+		}
+	case strings.ToLower(webrtc.MimeTypeH265):
+		return &parameterSets{
+			h265:   true,
+			types:  []byte{h265NALUTypeVPS, h265NALUTypeSPS, h265NALUTypePPS},
+			latest: map[byte][]byte{},
+		}
+	default:
+		return nil
+	}
+}
+
+// update keeps the parameter sets of the given Annex B frame. It returns whether or not the
+// frame has every parameter set and whether or not it is a key frame.
+func (p *parameterSets) update(frame []byte) (bool, bool) {
+	var keyFrame bool
+	found := map[byte]bool{}
+	for _, nalu := range annexBNALUs(frame) {
+		if len(nalu) == 0 {
+			continue
+		}
+		naluType := nalu[0] & 0x1f
+		if p.h265 {
+			naluType = (nalu[0] >> 1) & 0x3f
+		}
+		switch {
+		case p.isParameterSet(naluType):
+			p.latest[naluType] = append([]byte(nil), nalu...)
+			found[naluType] = true
+		case p.h265 && naluType >= h265NALUTypeFirstIRAP && naluType <= h265NALUTypeLastIRAP,
+			!p.h265 && naluType == h264NALUTypeIDR:
+			keyFrame = true
+		}
+	}
+	return len(found) == len(p.types), keyFrame
+}
+
+func (p *parameterSets) isParameterSet(naluType byte) bool {
+	for _, t := range p.types {
+		if t == naluType {
+			return true
+		}
+	}
+	return false
+}
+
+// prepend returns the given Annex B frame preceded by the latest parameter sets. The frame
+// is returned as is if not every parameter set was seen yet.
+func (p *parameterSets) prepend(frame []byte) []byte {
+	var prepended []byte
+	for _, t := range p.types {
+		nalu, ok := p.latest[t]
+		if !ok {
+			return frame
+		}
+		prepended = append(prepended, 0, 0, 0, 1)
+		prepended = append(prepended, nalu...)
+	}
+	return append(prepended, frame...)
+}
@@ -0,0 +1,70 @@
+package gostream
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+func TestParameterSets(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0xc0, 0x1f}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0x65, 0x88, 0x84, 0x21}
+	nonIDR := []byte{0x41, 0x9a, 0x02, 0x04}
+	annexB := func(nalus ...[]byte) []byte {
+		var data []byte
+		for _, nalu := range nalus {
+			data = append(data, 0, 0, 0, 1)
+			data = append(data, nalu...)
+		}
+		return data
+	}
+	sent := func(w *fakeTrackLocalWriter, nalu []byte) bool {
+		return bytes.Contains(bytes.Join(w.payloads, nil), nalu)
+	}
+
+	test.That(t, newParameterSets(webrtc.MimeTypeVP8), test.ShouldBeNil)
+
+	h264 := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000},
+		PayloadType:        102,
+	}
+	track := newVideoTrackLocalStaticSample(
+		[]webrtc.RTPCodecCapability{h264.RTPCodecCapability}, "video", "", "stream", newMediaClock(), 0, 0)
+	var binds int
+	track.rtpTrack.onBind = func() { binds++ }
+
+	var first, second fakeTrackLocalWriter
+	_, err := track.Bind(&fakeTrackLocalContext{
+		id: "first", ssrc: 1, writeStream: &first, codecs: []webrtc.RTPCodecParameters{h264},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, binds, test.ShouldEqual, 1)
+	test.That(t, track.WriteData(0, annexB(sps, pps, idr), 0, time.Now()), test.ShouldBeNil)
+	test.That(t, track.WriteData(0, annexB(nonIDR), 0, time.Now()), test.ShouldBeNil)
+
+	// a peer joining after the parameter sets went out gets them with its first key frame.
+	_, err = track.Bind(&fakeTrackLocalContext{
+		id: "second", ssrc: 2, writeStream: &second, codecs: []webrtc.RTPCodecParameters{h264},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, binds, test.ShouldEqual, 2)
+	test.That(t, track.WriteData(0, annexB(nonIDR), 0, time.Now()), test.ShouldBeNil)
+	test.That(t, sent(&second, sps), test.ShouldBeFalse)
+
+	first.payloads = nil
+	test.That(t, track.WriteData(0, annexB(idr), 0, time.Now()), test.ShouldBeNil)
+	test.That(t, sent(&second, sps), test.ShouldBeTrue)
+	test.That(t, sent(&second, pps), test.ShouldBeTrue)
+	test.That(t, sent(&second, idr), test.ShouldBeTrue)
+	test.That(t, sent(&first, sps), test.ShouldBeFalse)
+	test.That(t, sent(&first, idr), test.ShouldBeTrue)
+
+	// only the first key frame of a binding needs them.
+	second.payloads = nil
+	test.That(t, track.WriteData(0, annexB(idr), 0, time.Now()), test.ShouldBeNil)
+	test.That(t, sent(&second, sps), test.ShouldBeFalse)
+}
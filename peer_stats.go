@@ -0,0 +1,224 @@
+package gostream
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// PeerStats are statistics about the connection to a single peer and the streams sent to it.
+type PeerStats struct {
+	// ID identifies the peer for as long as it is connected.
+	ID string
+	// RoundTripTime is the latest round trip time ICE measured on the selected candidate pair.
+	RoundTripTime time.Duration
+	// BytesSent and BytesReceived count everything sent over the connection, including
+	// signaling and data channels.
+	BytesSent     uint64
+	BytesReceived uint64
+	// Bitrate is the rate, in bits per second, at which data was sent to the peer over the
+	// latest statsSampleInterval, no matter how often stats are gathered.
+	Bitrate float64
+	// SelectedCandidatePair is the pair of ICE candidates the connection uses, if one has
+	// been selected.
+	SelectedCandidatePair *CandidatePairStats
+	// Streams has the stats of each stream sent to the peer.
+	Streams []PeerStreamStats
+}
+
+// CandidatePairStats describes the local and remote ICE candidates of a connection.
+type CandidatePairStats struct {
+	Local  CandidateStats
+	Remote CandidateStats
+}
+
+// CandidateStats describes an ICE candidate.
+type CandidateStats struct {
+	Address string
+	Port    uint16
+	// Protocol is either udp or tcp.
+	Protocol string
+	// Type is one of host, srflx, prflx or relay. A relay candidate means the media goes
+	// through a TURN server.
+	Type string
+}
+
+// PeerStreamStats are statistics about a stream sent to a peer.
+type PeerStreamStats struct {
+	Name   string
+	Tracks []PeerTrackStats
+}
+
+// PeerTrackStats are statistics about a track sent to a peer.
+type PeerTrackStats struct {
+	// Kind is either audio or video.
+	Kind string
+	// RID is the simulcast layer sent, if any.
+	RID      string
+	SSRC     webrtc.SSRC
+	MimeType string
+	// PacketsSent and BytesSent count the RTP sent, including retransmissions.
+	PacketsSent uint64
+	BytesSent   uint64
+	// Bitrate is the rate, in bits per second, at which RTP was sent over the latest
+	// statsSampleInterval.
+	Bitrate float64
+	// FractionLost, TotalLost and Jitter are from the peer's latest receiver report.
+	FractionLost float64
+	TotalLost    uint32
+	Jitter       time.Duration
+}
+
+// statsSampleInterval is how often what was sent to a peer is sampled to compute its bitrates.
+const statsSampleInterval = time.Second
+
+// statsTrack is a track that knows what it sent to each peer.
+type statsTrack interface {
+	webrtc.TrackLocal
+	bindingStats(ssrc webrtc.SSRC) (bindingStats, bool)
+}
+
+// peerStream is a stream sent to a peer along with the senders of its tracks.
+type peerStream struct {
+	stream  Stream
+	senders []*webrtc.RTPSender
+}
+
+// gatherStats gathers the stats of the connection to the given peer and of all streams sent
+// to it. The bitrates are those computed by the latest sampleStats.
+func (p *activePeer) gatherStats(pc *webrtc.PeerConnection, streams []peerStream) PeerStats {
+	stats := p.countStats(pc, streams)
+
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	stats.Bitrate = p.bitrate
+	for i := range stats.Streams {
+		for j := range stats.Streams[i].Tracks {
+			stats.Streams[i].Tracks[j].Bitrate = p.trackBitrates[stats.Streams[i].Tracks[j].SSRC]
+		}
+	}
+	return stats
+}
+
+// sampleStats computes the bitrates of the given peer from what was sent to it since the
+// previous sample. It is called every statsSampleInterval so that the bitrates do not depend
+// on how often, or by how many callers, stats are gathered.
+func (p *activePeer) sampleStats(pc *webrtc.PeerConnection, streams []peerStream) {
+	stats := p.countStats(pc, streams)
+
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	now := time.Now()
+	interval := now.Sub(p.lastSampleAt)
+	p.lastSampleAt = now
+
+	p.bitrate = bitrate(stats.BytesSent, p.lastBytesSent, interval)
+	p.lastBytesSent = stats.BytesSent
+	// only the tracks sampled this time are kept so that those of removed streams do not pile up.
+	trackBytesSent := make(map[webrtc.SSRC]uint64, len(p.lastTrackBytesSent))
+	trackBitrates := make(map[webrtc.SSRC]float64, len(p.lastTrackBytesSent))
+	for _, streamStats := range stats.Streams {
+		for _, trackStats := range streamStats.Tracks {
+			trackBitrates[trackStats.SSRC] = bitrate(trackStats.BytesSent, p.lastTrackBytesSent[trackStats.SSRC], interval)
+			trackBytesSent[trackStats.SSRC] = trackStats.BytesSent
+		}
+	}
+	p.lastTrackBytesSent = trackBytesSent
+	p.trackBitrates = trackBitrates
+}
+
+// countStats gathers the stats of the connection to the given peer and of all streams sent
+// to it, leaving out the bitrates.
+func (p *activePeer) countStats(pc *webrtc.PeerConnection, streams []peerStream) PeerStats {
+	stats := PeerStats{ID: p.id}
+	report := pc.GetStats()
+	for _, stat := range report {
+		if transportStats, ok := stat.(webrtc.TransportStats); ok {
+			stats.BytesSent += transportStats.BytesSent
+			stats.BytesReceived += transportStats.BytesReceived
+		}
+	}
+
+	if pair := selectedCandidatePair(pc); pair != nil {
+		stats.SelectedCandidatePair = &CandidatePairStats{
+			Local:  candidateStats(pair.Local),
+			Remote: candidateStats(pair.Remote),
+		}
+		if pairStats, ok := report.GetICECandidatePairStats(pair); ok {
+			stats.RoundTripTime = time.Duration(pairStats.CurrentRoundTripTime * float64(time.Second))
+		}
+	}
+
+	for _, ps := range streams {
+		streamStats := PeerStreamStats{Name: ps.stream.Name()}
+		viewers := ps.stream.RTCPStats().Viewers
+		for _, sender := range ps.senders {
+			track, ok := sender.Track().(statsTrack)
+			if !ok {
+				continue
+			}
+			encodings := sender.GetParameters().Encodings
+			if len(encodings) == 0 {
+				continue
+			}
+			ssrc := encodings[0].SSRC
+			sent, ok := track.bindingStats(ssrc)
+			if !ok {
+				// the track is not bound until the connection is negotiated.
+				continue
+			}
+			trackStats := PeerTrackStats{
+				Kind:        track.Kind().String(),
+				RID:         track.RID(),
+				SSRC:        ssrc,
+				MimeType:    sent.mimeType,
+				PacketsSent: sent.packetsSent,
+				BytesSent:   sent.bytesSent,
+			}
+			if viewer, ok := viewers[ssrc]; ok {
+				trackStats.FractionLost = viewer.FractionLost
+				trackStats.TotalLost = viewer.TotalLost
+				if sent.clockRate != 0 {
+					trackStats.Jitter = time.Duration(viewer.Jitter) * time.Second / time.Duration(sent.clockRate)
+				}
+			}
+			streamStats.Tracks = append(streamStats.Tracks, trackStats)
+		}
+		stats.Streams = append(stats.Streams, streamStats)
+	}
+	return stats
+}
+
+// selectedCandidatePair returns the ICE candidate pair the given connection uses, if any.
+func selectedCandidatePair(pc *webrtc.PeerConnection) *webrtc.ICECandidatePair {
+	sctp := pc.SCTP()
+	if sctp == nil || sctp.Transport() == nil || sctp.Transport().ICETransport() == nil {
+		return nil
+	}
+	pair, err := sctp.Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil {
+		return nil
+	}
+	return pair
+}
+
+func candidateStats(candidate *webrtc.ICECandidate) CandidateStats {
+	if candidate == nil {
+		return CandidateStats{}
+	}
+	return CandidateStats{
+		Address:  candidate.Address,
+		Port:     candidate.Port,
+		Protocol: candidate.Protocol.String(),
+		Type:     candidate.Typ.String(),
+	}
+}
+
+// bitrate returns the rate, in bits per second, at which a byte count grew from the given
+// previous count over the given interval.
+func bitrate(bytes, previous uint64, interval time.Duration) float64 {
+	if interval <= 0 || bytes < previous {
+		return 0
+	}
+	return float64(bytes-previous) * 8 / interval.Seconds()
+}
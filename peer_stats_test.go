@@ -0,0 +1,73 @@
+package gostream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+func TestGatherPeerStats(t *testing.T) {
+	stream, err := NewStream(StreamConfig{
+		Name:                "cam",
+		VideoEncoderFactory: &slowVideoEncoderFactory{},
+		Simulcast:           []SimulcastLayer{{RID: "full"}},
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, pc.Close(), test.ShouldBeNil)
+	}()
+	trackLocal, _ := stream.VideoTrackLocal()
+	sender, err := pc.AddTrack(trackLocal)
+	test.That(t, err, test.ShouldBeNil)
+	ssrc := sender.GetParameters().Encodings[0].SSRC
+
+	peer := &activePeer{id: "peer", lastSampleAt: time.Now()}
+	streams := []peerStream{{stream, []*webrtc.RTPSender{sender}}}
+
+	// nothing is reported about a track that is not bound yet.
+	stats := peer.gatherStats(pc, streams)
+	test.That(t, stats.ID, test.ShouldEqual, "peer")
+	test.That(t, stats.SelectedCandidatePair, test.ShouldBeNil)
+	test.That(t, stats.Streams, test.ShouldHaveLength, 1)
+	test.That(t, stats.Streams[0].Name, test.ShouldEqual, "cam")
+	test.That(t, stats.Streams[0].Tracks, test.ShouldBeEmpty)
+
+	track := stream.(*basicStream).videoLayers[0].track
+	track.rtpTrack.bindings = []trackBinding{{ssrc: ssrc, clockRate: 90000, packetsSent: 10, bytesSent: 10000}}
+	stream.handleRTCP([]rtcp.Packet{&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+		{SSRC: uint32(ssrc), FractionLost: 128, TotalLost: 4, Jitter: 900},
+	}}})
+	peer.lastSampleAt = time.Now().Add(-time.Second)
+	peer.sampleStats(pc, streams)
+	stats = peer.gatherStats(pc, streams)
+	test.That(t, stats.Streams[0].Tracks, test.ShouldHaveLength, 1)
+	trackStats := stats.Streams[0].Tracks[0]
+	test.That(t, trackStats.Kind, test.ShouldEqual, "video")
+	test.That(t, trackStats.RID, test.ShouldEqual, "full")
+	test.That(t, trackStats.SSRC, test.ShouldEqual, ssrc)
+	test.That(t, trackStats.MimeType, test.ShouldEqual, "video/vp8")
+	test.That(t, trackStats.PacketsSent, test.ShouldEqual, 10)
+	test.That(t, trackStats.BytesSent, test.ShouldEqual, 10000)
+	test.That(t, trackStats.Bitrate, test.ShouldAlmostEqual, 80000, 1000)
+	test.That(t, trackStats.FractionLost, test.ShouldEqual, 0.5)
+	test.That(t, trackStats.TotalLost, test.ShouldEqual, 4)
+	test.That(t, trackStats.Jitter, test.ShouldEqual, 10*time.Millisecond)
+
+	// bitrates only change when sampled, however often stats are gathered.
+	track.rtpTrack.bindings[0].bytesSent = 15000
+	stats = peer.gatherStats(pc, streams)
+	test.That(t, stats.Streams[0].Tracks[0].BytesSent, test.ShouldEqual, 15000)
+	test.That(t, stats.Streams[0].Tracks[0].Bitrate, test.ShouldAlmostEqual, 80000, 1000)
+	peer.lastSampleAt = time.Now().Add(-time.Second)
+	peer.sampleStats(pc, streams)
+	stats = peer.gatherStats(pc, streams)
+	test.That(t, stats.Streams[0].Tracks[0].Bitrate, test.ShouldAlmostEqual, 40000, 1000)
+}
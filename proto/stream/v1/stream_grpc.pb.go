@@ -19,9 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	StreamService_ListStreams_FullMethodName  = "/proto.stream.v1.StreamService/ListStreams"
-	StreamService_AddStream_FullMethodName    = "/proto.stream.v1.StreamService/AddStream"
-	StreamService_RemoveStream_FullMethodName = "/proto.stream.v1.StreamService/RemoveStream"
+	StreamService_ListStreams_FullMethodName    = "/proto.stream.v1.StreamService/ListStreams"
+	StreamService_AddStream_FullMethodName      = "/proto.stream.v1.StreamService/AddStream"
+	StreamService_RemoveStream_FullMethodName   = "/proto.stream.v1.StreamService/RemoveStream"
+	StreamService_SetStreamLayer_FullMethodName = "/proto.stream.v1.StreamService/SetStreamLayer"
+	StreamService_MuteStream_FullMethodName     = "/proto.stream.v1.StreamService/MuteStream"
+	StreamService_GetStats_FullMethodName       = "/proto.stream.v1.StreamService/GetStats"
 )
 
 // StreamServiceClient is the client API for StreamService service.
@@ -36,6 +39,16 @@ type StreamServiceClient interface {
 	// is the last to be receiving the stream, it will attempt to be stopped to
 	// conserve resources.
 	RemoveStream(ctx context.Context, in *RemoveStreamRequest, opts ...grpc.CallOption) (*RemoveStreamResponse, error)
+	// SetStreamLayer switches the video of an added stream to the simulcast
+	// layer with the given RID without renegotiating the connection.
+	SetStreamLayer(ctx context.Context, in *SetStreamLayerRequest, opts ...grpc.CallOption) (*SetStreamLayerResponse, error)
+	// MuteStream mutes or unmutes the audio or video of an added stream for the
+	// calling client alone. Muted tracks stay negotiated but are sent nothing so
+	// they can be unmuted quickly without renegotiating the connection.
+	MuteStream(ctx context.Context, in *MuteStreamRequest, opts ...grpc.CallOption) (*MuteStreamResponse, error)
+	// GetStats returns statistics about the connection to the calling client and
+	// about the streams sent to it. Other peers are never included.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 }
 
 type streamServiceClient struct {
@@ -73,6 +86,33 @@ func (c *streamServiceClient) RemoveStream(ctx context.Context, in *RemoveStream
 	return out, nil
 }
 
+func (c *streamServiceClient) SetStreamLayer(ctx context.Context, in *SetStreamLayerRequest, opts ...grpc.CallOption) (*SetStreamLayerResponse, error) {
+	out := new(SetStreamLayerResponse)
+	err := c.cc.Invoke(ctx, StreamService_SetStreamLayer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *streamServiceClient) MuteStream(ctx context.Context, in *MuteStreamRequest, opts ...grpc.CallOption) (*MuteStreamResponse, error) {
+	out := new(MuteStreamResponse)
+	err := c.cc.Invoke(ctx, StreamService_MuteStream_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *streamServiceClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, StreamService_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StreamServiceServer is the server API for StreamService service.
 // All implementations must embed UnimplementedStreamServiceServer
 // for forward compatibility
@@ -85,6 +125,16 @@ type StreamServiceServer interface {
 	// is the last to be receiving the stream, it will attempt to be stopped to
 	// conserve resources.
 	RemoveStream(context.Context, *RemoveStreamRequest) (*RemoveStreamResponse, error)
+	// SetStreamLayer switches the video of an added stream to the simulcast
+	// layer with the given RID without renegotiating the connection.
+	SetStreamLayer(context.Context, *SetStreamLayerRequest) (*SetStreamLayerResponse, error)
+	// MuteStream mutes or unmutes the audio or video of an added stream for the
+	// calling client alone. Muted tracks stay negotiated but are sent nothing so
+	// they can be unmuted quickly without renegotiating the connection.
+	MuteStream(context.Context, *MuteStreamRequest) (*MuteStreamResponse, error)
+	// GetStats returns statistics about the connection to the calling client and
+	// about the streams sent to it. Other peers are never included.
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	mustEmbedUnimplementedStreamServiceServer()
 }
 
@@ -101,6 +151,15 @@ func (UnimplementedStreamServiceServer) AddStream(context.Context, *AddStreamReq
 func (UnimplementedStreamServiceServer) RemoveStream(context.Context, *RemoveStreamRequest) (*RemoveStreamResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveStream not implemented")
 }
+func (UnimplementedStreamServiceServer) SetStreamLayer(context.Context, *SetStreamLayerRequest) (*SetStreamLayerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStreamLayer not implemented")
+}
+func (UnimplementedStreamServiceServer) MuteStream(context.Context, *MuteStreamRequest) (*MuteStreamResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MuteStream not implemented")
+}
+func (UnimplementedStreamServiceServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
 func (UnimplementedStreamServiceServer) mustEmbedUnimplementedStreamServiceServer() {}
 
 // UnsafeStreamServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -168,6 +227,60 @@ func _StreamService_RemoveStream_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StreamService_SetStreamLayer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStreamLayerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StreamServiceServer).SetStreamLayer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StreamService_SetStreamLayer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StreamServiceServer).SetStreamLayer(ctx, req.(*SetStreamLayerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StreamService_MuteStream_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MuteStreamRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StreamServiceServer).MuteStream(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StreamService_MuteStream_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StreamServiceServer).MuteStream(ctx, req.(*MuteStreamRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StreamService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StreamServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StreamService_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StreamServiceServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StreamService_ServiceDesc is the grpc.ServiceDesc for StreamService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -187,6 +300,18 @@ var StreamService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveStream",
 			Handler:    _StreamService_RemoveStream_Handler,
 		},
+		{
+			MethodName: "SetStreamLayer",
+			Handler:    _StreamService_SetStreamLayer_Handler,
+		},
+		{
+			MethodName: "MuteStream",
+			Handler:    _StreamService_MuteStream_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _StreamService_GetStats_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/stream/v1/stream.proto",
@@ -0,0 +1,245 @@
+package gostream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+// An RTCPEvent is feedback about a stream's media sent back by a viewer over RTCP. It is
+// one of ReceiverReportEvent, PictureLossEvent, FullIntraRequestEvent, NACKEvent or
+// REMBEvent.
+type RTCPEvent interface {
+	isRTCPEvent()
+}
+
+// A ReceiverReportEvent reports how well a viewer is receiving the media of an SSRC.
+type ReceiverReportEvent struct {
+	SSRC webrtc.SSRC
+	// FractionLost is the fraction, from 0 to 1, of packets lost since the previous report.
+	FractionLost float64
+	// TotalLost is the number of packets lost since the viewer started receiving.
+	TotalLost uint32
+	// Jitter is the interarrival jitter in units of the media's clock rate.
+	Jitter uint32
+	// LastSenderReport and Delay are the middle 32 bits of the NTP timestamp of the last
+	// sender report received and the delay since then in units of 1/65536 seconds.
+	LastSenderReport uint32
+	Delay            uint32
+}
+
+// A PictureLossEvent is a viewer's request for a key frame after it lost video.
+type PictureLossEvent struct {
+	SSRC webrtc.SSRC
+}
+
+// A FullIntraRequestEvent is a viewer's request for a key frame.
+type FullIntraRequestEvent struct {
+	SSRC webrtc.SSRC
+}
+
+// A NACKEvent lists the sequence numbers of packets a viewer did not receive.
+type NACKEvent struct {
+	SSRC            webrtc.SSRC
+	SequenceNumbers []uint16
+}
+
+// A REMBEvent is a viewer's estimate of the maximum bitrate it can receive for the given SSRCs.
+type REMBEvent struct {
+	SSRCs []webrtc.SSRC
+	// Bitrate is in bits per second.
+	Bitrate float64
+}
+
+func (ReceiverReportEvent) isRTCPEvent()   {}
+func (PictureLossEvent) isRTCPEvent()      {}
+func (FullIntraRequestEvent) isRTCPEvent() {}
+func (NACKEvent) isRTCPEvent()             {}
+func (REMBEvent) isRTCPEvent()             {}
+
+// RTCPStats is a snapshot of the RTCP feedback a stream has received.
+type RTCPStats struct {
+	// PictureLossCount, FullIntraRequestCount and NACKCount are totals across all viewers.
+	PictureLossCount      uint64
+	FullIntraRequestCount uint64
+	NACKCount             uint64
+	// Viewers holds the feedback about each SSRC the stream currently sends.
+	Viewers map[webrtc.SSRC]ViewerRTCPStats
+}
+
+// ViewerRTCPStats is the RTCP feedback about the media of a single SSRC.
+type ViewerRTCPStats struct {
+	// FractionLost, TotalLost and Jitter are from the latest receiver report.
+	FractionLost float64
+	TotalLost    uint32
+	Jitter       uint32
+	// LastReport is when the latest receiver report arrived.
+	LastReport time.Time
+
+	PictureLossCount      uint64
+	FullIntraRequestCount uint64
+	NACKCount             uint64
+	// NACKedPackets is how many packets were asked for across all NACKs.
+	NACKedPackets uint64
+	// REMBBitrate is the latest receiver estimated maximum bitrate in bits per second.
+	REMBBitrate float64
+}
+
+// rtcpFeedback turns the RTCP received by a stream into events and aggregates it into stats.
+type rtcpFeedback struct {
+	mu            sync.Mutex
+	stats         RTCPStats
+	handlers      map[int]func(RTCPEvent)
+	nextHandlerID int
+}
+
+func newRTCPFeedback() *rtcpFeedback {
+	return &rtcpFeedback{
+		stats:    RTCPStats{Viewers: map[webrtc.SSRC]ViewerRTCPStats{}},
+		handlers: map[int]func(RTCPEvent){},
+	}
+}
+
+// addHandler calls the given handler with every event until the returned function is called.
+func (f *rtcpFeedback) addHandler(handler func(RTCPEvent)) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.nextHandlerID
+	f.nextHandlerID++
+	f.handlers[id] = handler
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.handlers, id)
+	}
+}
+
+// handle decodes the given packets into events, records them and passes them to the
+// handlers. The events are also returned.
+func (f *rtcpFeedback) handle(packets []rtcp.Packet) []RTCPEvent {
+	events := rtcpEvents(packets)
+	if len(events) == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	now := time.Now()
+	for _, event := range events {
+		f.record(event, now)
+	}
+	handlers := make([]func(RTCPEvent), 0, len(f.handlers))
+	for _, handler := range f.handlers {
+		handlers = append(handlers, handler)
+	}
+	f.mu.Unlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+	return events
+}
+
+// record adds the given event to the stats.
+func (f *rtcpFeedback) record(event RTCPEvent, now time.Time) {
+	switch event := event.(type) {
+	case ReceiverReportEvent:
+		viewer := f.stats.Viewers[event.SSRC]
+		viewer.FractionLost = event.FractionLost
+		viewer.TotalLost = event.TotalLost
+		viewer.Jitter = event.Jitter
+		viewer.LastReport = now
+		f.stats.Viewers[event.SSRC] = viewer
+	case PictureLossEvent:
+		viewer := f.stats.Viewers[event.SSRC]
+		viewer.PictureLossCount++
+		f.stats.Viewers[event.SSRC] = viewer
+		f.stats.PictureLossCount++
+	case FullIntraRequestEvent:
+		viewer := f.stats.Viewers[event.SSRC]
+		viewer.FullIntraRequestCount++
+		f.stats.Viewers[event.SSRC] = viewer
+		f.stats.FullIntraRequestCount++
+	case NACKEvent:
+		viewer := f.stats.Viewers[event.SSRC]
+		viewer.NACKCount++
+		viewer.NACKedPackets += uint64(len(event.SequenceNumbers))
+		f.stats.Viewers[event.SSRC] = viewer
+		f.stats.NACKCount++
+	case REMBEvent:
+		for _, ssrc := range event.SSRCs {
+			viewer := f.stats.Viewers[ssrc]
+			viewer.REMBBitrate = event.Bitrate
+			f.stats.Viewers[ssrc] = viewer
+		}
+	}
+}
+
+// forget drops the stats of the given SSRC, such as once its viewer is gone.
+func (f *rtcpFeedback) forget(ssrc webrtc.SSRC) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.stats.Viewers, ssrc)
+}
+
+// snapshot returns a copy of the stats.
+func (f *rtcpFeedback) snapshot() RTCPStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := f.stats
+	stats.Viewers = make(map[webrtc.SSRC]ViewerRTCPStats, len(f.stats.Viewers))
+	for ssrc, viewer := range f.stats.Viewers {
+		stats.Viewers[ssrc] = viewer
+	}
+	return stats
+}
+
+// rtcpEvents decodes the feedback in the given packets into events. Packets that are not
+// feedback about sent media are ignored.
+func rtcpEvents(packets []rtcp.Packet) []RTCPEvent {
+	var events []RTCPEvent
+	addReports := func(reports []rtcp.ReceptionReport) {
+		for _, report := range reports {
+			events = append(events, ReceiverReportEvent{
+				SSRC:             webrtc.SSRC(report.SSRC),
+				FractionLost:     float64(report.FractionLost) / 256,
+				TotalLost:        report.TotalLost,
+				Jitter:           report.Jitter,
+				LastSenderReport: report.LastSenderReport,
+				Delay:            report.Delay,
+			})
+		}
+	}
+	for _, packet := range packets {
+		switch packet := packet.(type) {
+		case *rtcp.ReceiverReport:
+			addReports(packet.Reports)
+		case *rtcp.SenderReport:
+			addReports(packet.Reports)
+		case *rtcp.PictureLossIndication:
+			events = append(events, PictureLossEvent{SSRC: webrtc.SSRC(packet.MediaSSRC)})
+		case *rtcp.FullIntraRequest:
+			// the media SSRC of a FIR is unused (RFC 5104 section 4.3.1) as each entry names
+			// the sender it requests a key frame of.
+			for _, entry := range packet.FIR {
+				events = append(events, FullIntraRequestEvent{SSRC: webrtc.SSRC(entry.SSRC)})
+			}
+		case *rtcp.TransportLayerNack:
+			var sequenceNumbers []uint16
+			for _, pair := range packet.Nacks {
+				sequenceNumbers = append(sequenceNumbers, pair.PacketList()...)
+			}
+			events = append(events, NACKEvent{SSRC: webrtc.SSRC(packet.MediaSSRC), SequenceNumbers: sequenceNumbers})
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			ssrcs := make([]webrtc.SSRC, 0, len(packet.SSRCs))
+			for _, ssrc := range packet.SSRCs {
+				ssrcs = append(ssrcs, webrtc.SSRC(ssrc))
+			}
+			events = append(events, REMBEvent{SSRCs: ssrcs, Bitrate: float64(packet.Bitrate)})
+		}
+	}
+	return events
+}
@@ -0,0 +1,103 @@
+package gostream
+
+import (
+	"testing"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+func TestRTCPFeedback(t *testing.T) {
+	feedback := newRTCPFeedback()
+	var events []RTCPEvent
+	remove := feedback.addHandler(func(event RTCPEvent) {
+		events = append(events, event)
+	})
+
+	feedback.handle([]rtcp.Packet{
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1, FractionLost: 64, TotalLost: 10, Jitter: 90}}},
+		&rtcp.PictureLossIndication{MediaSSRC: 1},
+		&rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: 2, SequenceNumber: 1}}},
+		&rtcp.TransportLayerNack{MediaSSRC: 1, Nacks: rtcp.NackPairsFromSequenceNumbers([]uint16{5, 6, 9})},
+		&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 500000, SSRCs: []uint32{1, 2}},
+		&rtcp.Goodbye{Sources: []uint32{1}},
+	})
+
+	test.That(t, events, test.ShouldResemble, []RTCPEvent{
+		ReceiverReportEvent{SSRC: 1, FractionLost: 0.25, TotalLost: 10, Jitter: 90},
+		PictureLossEvent{SSRC: 1},
+		FullIntraRequestEvent{SSRC: 2},
+		NACKEvent{SSRC: 1, SequenceNumbers: []uint16{5, 6, 9}},
+		REMBEvent{SSRCs: []webrtc.SSRC{1, 2}, Bitrate: 500000},
+	})
+
+	stats := feedback.snapshot()
+	test.That(t, stats.PictureLossCount, test.ShouldEqual, 1)
+	test.That(t, stats.FullIntraRequestCount, test.ShouldEqual, 1)
+	test.That(t, stats.NACKCount, test.ShouldEqual, 1)
+	test.That(t, stats.Viewers, test.ShouldHaveLength, 2)
+	viewer := stats.Viewers[1]
+	test.That(t, viewer.FractionLost, test.ShouldEqual, 0.25)
+	test.That(t, viewer.TotalLost, test.ShouldEqual, 10)
+	test.That(t, viewer.Jitter, test.ShouldEqual, 90)
+	test.That(t, viewer.LastReport.IsZero(), test.ShouldBeFalse)
+	test.That(t, viewer.PictureLossCount, test.ShouldEqual, 1)
+	test.That(t, viewer.NACKedPackets, test.ShouldEqual, 3)
+	test.That(t, viewer.REMBBitrate, test.ShouldEqual, 500000)
+	test.That(t, stats.Viewers[2].FullIntraRequestCount, test.ShouldEqual, 1)
+
+	remove()
+	feedback.handle([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1}})
+	test.That(t, events, test.ShouldHaveLength, 5)
+	test.That(t, feedback.snapshot().PictureLossCount, test.ShouldEqual, 2)
+
+	// a FIR requests a key frame of every sender it has an entry for.
+	feedback.handle([]rtcp.Packet{&rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: 3}, {SSRC: 4}}}})
+	stats = feedback.snapshot()
+	test.That(t, stats.FullIntraRequestCount, test.ShouldEqual, 3)
+	test.That(t, stats.Viewers[3].FullIntraRequestCount, test.ShouldEqual, 1)
+	test.That(t, stats.Viewers[4].FullIntraRequestCount, test.ShouldEqual, 1)
+}
+
+func TestRTCPStatsForgetUnboundViewers(t *testing.T) {
+	stream, err := NewStream(StreamConfig{
+		VideoEncoderFactory: &slowVideoEncoderFactory{},
+		Simulcast:           []SimulcastLayer{{RID: "f"}, {RID: "q", ScaleResolutionDownBy: 4}},
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+	bs := stream.(*basicStream)
+	full, quarter := bs.videoLayers[0].track, bs.videoLayers[1].track
+
+	var writer fakeTrackLocalWriter
+	leaving := &fakeTrackLocalContext{id: "leaving", ssrc: 1, writeStream: &writer}
+	switching := &fakeTrackLocalContext{id: "switching", ssrc: 2, writeStream: &writer}
+	for _, ctx := range []*fakeTrackLocalContext{leaving, switching} {
+		_, err := full.Bind(ctx)
+		test.That(t, err, test.ShouldBeNil)
+	}
+	stream.handleRTCP([]rtcp.Packet{
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1}, {SSRC: 2}}},
+	})
+	test.That(t, stream.RTCPStats().Viewers, test.ShouldHaveLength, 2)
+
+	// a viewer that is gone is forgotten.
+	test.That(t, full.Unbind(leaving), test.ShouldBeNil)
+	viewers := stream.RTCPStats().Viewers
+	test.That(t, viewers, test.ShouldHaveLength, 1)
+	test.That(t, viewers, test.ShouldContainKey, webrtc.SSRC(2))
+
+	// a viewer switching layers is not.
+	handoff, ok := full.handoff(2)
+	test.That(t, ok, test.ShouldBeTrue)
+	quarter.expectHandoff(2, handoff)
+	test.That(t, full.Unbind(switching), test.ShouldBeNil)
+	_, err = quarter.Bind(switching)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, stream.RTCPStats().Viewers, test.ShouldContainKey, webrtc.SSRC(2))
+
+	test.That(t, quarter.Unbind(switching), test.ShouldBeNil)
+	test.That(t, stream.RTCPStats().Viewers, test.ShouldBeEmpty)
+}
@@ -0,0 +1,56 @@
+package gostream
+
+import (
+	"github.com/pion/rtp"
+)
+
+// DefaultNACKHistorySize is a suitable number of sent video packets to keep per viewer.
+const DefaultNACKHistorySize = 512
+
+// maxNACKHistorySize bounds the history to half of the sequence number space so that a
+// sequence number always maps to at most one kept packet.
+const maxNACKHistorySize = 1 << 15
+
+// packetHistory keeps the most recently sent RTP packets of a binding so that the ones a
+// viewer reports lost can be sent again. Its memory is bounded by its size times the MTU
+// since packet buffers are reused.
+type packetHistory struct {
+	packets []historyPacket
+	mask    uint16
+}
+
+type historyPacket struct {
+	header  rtp.Header
+	payload []byte
+	set     bool
+}
+
+// newPacketHistory returns a history of at least the given size, rounded up to a power
+// of two so that sequence numbers wrap around cleanly.
+func newPacketHistory(size int) *packetHistory {
+	rounded := 1
+	for rounded < size && rounded < maxNACKHistorySize {
+		rounded <<= 1
+	}
+	return &packetHistory{
+		packets: make([]historyPacket, rounded),
+		mask:    uint16(rounded - 1),
+	}
+}
+
+// add keeps a copy of the given packet, replacing the oldest one.
+func (h *packetHistory) add(header *rtp.Header, payload []byte) {
+	entry := &h.packets[header.SequenceNumber&h.mask]
+	entry.header = header.Clone()
+	entry.payload = append(entry.payload[:0], payload...)
+	entry.set = true
+}
+
+// get returns the packet sent with the given sequence number if it is still kept.
+func (h *packetHistory) get(sequenceNumber uint16) (*rtp.Header, []byte, bool) {
+	entry := &h.packets[sequenceNumber&h.mask]
+	if !entry.set || entry.header.SequenceNumber != sequenceNumber {
+		return nil, nil, false
+	}
+	return &entry.header, entry.payload, true
+}
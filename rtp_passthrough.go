@@ -0,0 +1,335 @@
+package gostream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/google/uuid"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/utils"
+)
+
+// An RTPPassthroughStream is a Stream that relays RTP packets that are already encoded and
+// packetized, such as those received from another peer, read from a file or a UDP socket,
+// instead of encoding frames. Every viewer receives an RTP stream of its own with the SSRC,
+// payload type, sequence numbers and timestamps it negotiated. It does not accept frames or
+// chunks and its bitrate cannot be changed.
+type RTPPassthroughStream interface {
+	Stream
+
+	// WriteVideoRTP relays the given video packet to every viewer.
+	WriteVideoRTP(packet *rtp.Packet) error
+
+	// WriteAudioRTP relays the given audio packet to every viewer.
+	WriteAudioRTP(packet *rtp.Packet) error
+}
+
+// An RTPPassthroughStreamConfig describes how an RTPPassthroughStream should be managed.
+type RTPPassthroughStreamConfig struct {
+	Name string
+
+	// VideoCodec and AudioCodec are the codecs of the packets relayed. At least one must be set.
+	VideoCodec *webrtc.RTPCodecCapability
+	AudioCodec *webrtc.RTPCodecCapability
+
+	// NACKHistorySize is how many of the most recently relayed video packets are kept for each
+	// viewer in order to retransmit the ones it reports lost, like StreamConfig.NACKHistorySize.
+	NACKHistorySize int
+
+	// FECOverhead is the fraction of video packets, between 0 and 1, additionally sent as
+	// forward error correction to viewers that negotiated it, like StreamConfig.FECOverhead.
+	// Zero disables it.
+	FECOverhead float64
+
+	// OnKeyFrameRequest is called when a viewer needs a key frame. Since the stream cannot
+	// produce one itself, it should be asked for from the source of the packets, e.g. by
+	// sending it a picture loss indication. It is called on its own goroutine and requests
+	// made in quick succession are coalesced into one.
+	OnKeyFrameRequest func()
+
+	Logger golog.Logger
+}
+
+// NewRTPPassthroughStream returns a newly configured stream that relays RTP packets and can
+// begin to handle new connections.
+func NewRTPPassthroughStream(config RTPPassthroughStreamConfig) (RTPPassthroughStream, error) {
+	logger := config.Logger
+	if logger == nil {
+		logger = golog.Global()
+	}
+	if config.VideoCodec == nil && config.AudioCodec == nil {
+		return nil, errors.New("at least one audio or video codec must be set")
+	}
+	if config.FECOverhead < 0 || config.FECOverhead > 1 {
+		return nil, fmt.Errorf("forward error correction overhead must be between 0 and 1, not %v", config.FECOverhead)
+	}
+
+	name := config.Name
+	if name == "" {
+		name = uuid.NewString()
+	}
+
+	var videoTrackLocal *trackLocalStaticRTP
+	if config.VideoCodec != nil {
+		videoTrackLocal = newtrackLocalStaticRTP([]webrtc.RTPCodecCapability{*config.VideoCodec}, "video", name)
+		videoTrackLocal.nackHistorySize = config.NACKHistorySize
+		videoTrackLocal.fecOverhead = config.FECOverhead
+		videoTrackLocal.rewrite = true
+	}
+
+	var audioTrackLocal *trackLocalStaticRTP
+	if config.AudioCodec != nil {
+		audioTrackLocal = newtrackLocalStaticRTP([]webrtc.RTPCodecCapability{*config.AudioCodec}, "audio", name)
+		audioTrackLocal.rewrite = true
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	ps := &rtpPassthroughStream{
+		name:              name,
+		config:            config,
+		streamingReadyCh:  make(chan struct{}),
+		videoTrackLocal:   videoTrackLocal,
+		audioTrackLocal:   audioTrackLocal,
+		rtcpFeedback:      newRTCPFeedback(),
+		logger:            logger,
+		shutdownCtx:       ctx,
+		shutdownCtxCancel: cancelFunc,
+
+		keyFrameRequestInterval: minKeyFrameRequestInterval,
+	}
+	// viewers joining a running stream would otherwise wait for the source's next key frame.
+	if videoTrackLocal != nil {
+		videoTrackLocal.onBind = ps.RequestKeyFrame
+		videoTrackLocal.onUnbind = ps.rtcpFeedback.forget
+	}
+	if audioTrackLocal != nil {
+		audioTrackLocal.onUnbind = ps.rtcpFeedback.forget
+	}
+	return ps, nil
+}
+
+type rtpPassthroughStream struct {
+	mu               sync.RWMutex
+	name             string
+	config           RTPPassthroughStreamConfig
+	started          bool
+	streamingReadyCh chan struct{}
+
+	videoTrackLocal *trackLocalStaticRTP
+	audioTrackLocal *trackLocalStaticRTP
+
+	rtcpFeedback *rtcpFeedback
+
+	// keyFrameRequestInterval is the least time in between key frame requests made to the
+	// source, minKeyFrameRequestInterval unless changed by tests.
+	keyFrameRequestInterval time.Duration
+	// keyFrameMu guards when a key frame was last requested from the source and the timer of
+	// a request waiting to be made, if any.
+	keyFrameMu          sync.Mutex
+	lastKeyFrameRequest time.Time
+	keyFrameTimer       *time.Timer
+
+	shutdownCtx       context.Context
+	shutdownCtxCancel func()
+	logger            golog.Logger
+}
+
+var errPassthroughInput = errors.New("rtp passthrough stream only accepts rtp packets")
+
+func (ps *rtpPassthroughStream) Name() string {
+	return ps.name
+}
+
+func (ps *rtpPassthroughStream) Start() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.started {
+		return
+	}
+	ps.started = true
+	close(ps.streamingReadyCh)
+}
+
+func (ps *rtpPassthroughStream) Stop() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if !ps.started {
+		close(ps.streamingReadyCh)
+	}
+
+	ps.started = false
+	ps.shutdownCtxCancel()
+
+	// a deferred key frame request is not made for viewers that are gone.
+	ps.keyFrameMu.Lock()
+	if ps.keyFrameTimer != nil {
+		ps.keyFrameTimer.Stop()
+		ps.keyFrameTimer = nil
+	}
+	ps.keyFrameMu.Unlock()
+
+	// reset
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	ps.shutdownCtx = ctx
+	ps.shutdownCtxCancel = cancelFunc
+	ps.streamingReadyCh = make(chan struct{})
+}
+
+func (ps *rtpPassthroughStream) StreamingReady() (<-chan struct{}, context.Context) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.streamingReadyCh, ps.shutdownCtx
+}
+
+func (ps *rtpPassthroughStream) InputVideoFrames(props prop.Video) (chan<- MediaReleasePair[image.Image], error) {
+	return nil, errPassthroughInput
+}
+
+func (ps *rtpPassthroughStream) InputAudioChunks(props prop.Audio) (chan<- MediaReleasePair[wave.Audio], error) {
+	return nil, errPassthroughInput
+}
+
+func (ps *rtpPassthroughStream) WriteVideoRTP(packet *rtp.Packet) error {
+	if ps.videoTrackLocal == nil {
+		return errors.New("no video in stream")
+	}
+	return ps.videoTrackLocal.WriteRTP(packet)
+}
+
+func (ps *rtpPassthroughStream) WriteAudioRTP(packet *rtp.Packet) error {
+	if ps.audioTrackLocal == nil {
+		return errors.New("no audio in stream")
+	}
+	return ps.audioTrackLocal.WriteRTP(packet)
+}
+
+func (ps *rtpPassthroughStream) SetVideoBitrate(bitrate int) error {
+	return errBitrateUnsupported
+}
+
+func (ps *rtpPassthroughStream) SetAudioBitrate(bitrate int) error {
+	return errBitrateUnsupported
+}
+
+// RequestKeyFrame asks the source for a key frame by calling OnKeyFrameRequest, at most once
+// per minKeyFrameRequestInterval. A request made sooner is deferred until the interval has
+// passed rather than dropped, since the key frame already requested may have been sent
+// before the viewer asking now could decode it. Stopping the stream drops a deferred request.
+func (ps *rtpPassthroughStream) RequestKeyFrame() {
+	if ps.config.OnKeyFrameRequest == nil {
+		return
+	}
+	ps.keyFrameMu.Lock()
+	defer ps.keyFrameMu.Unlock()
+	if ps.keyFrameTimer != nil {
+		return
+	}
+	wait := ps.keyFrameRequestInterval - time.Since(ps.lastKeyFrameRequest)
+	if wait <= 0 {
+		ps.lastKeyFrameRequest = time.Now()
+		// requests come from within pion, such as when a track is bound, which the
+		// callback must not hold up.
+		utils.PanicCapturingGo(ps.config.OnKeyFrameRequest)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		ps.keyFrameMu.Lock()
+		// the timer may have fired just as Stop stopped it.
+		if ps.keyFrameTimer != timer {
+			ps.keyFrameMu.Unlock()
+			return
+		}
+		ps.keyFrameTimer = nil
+		ps.lastKeyFrameRequest = time.Now()
+		ps.keyFrameMu.Unlock()
+		utils.PanicCapturingGo(ps.config.OnKeyFrameRequest)
+	})
+	ps.keyFrameTimer = timer
+}
+
+func (ps *rtpPassthroughStream) SetMaxTemporalLayer(ssrc webrtc.SSRC, layer int) error {
+	return errors.New("rtp passthrough stream does not know the temporal layers of its packets")
+}
+
+func (ps *rtpPassthroughStream) AddRTCPEventHandler(handler func(RTCPEvent)) func() {
+	return ps.rtcpFeedback.addHandler(handler)
+}
+
+func (ps *rtpPassthroughStream) RTCPStats() RTCPStats {
+	return ps.rtcpFeedback.snapshot()
+}
+
+// VideoFrameStats is always empty since the stream never sees frames.
+func (ps *rtpPassthroughStream) VideoFrameStats() VideoFrameStats {
+	return VideoFrameStats{}
+}
+
+func (ps *rtpPassthroughStream) VideoTrackLocal() (webrtc.TrackLocal, bool) {
+	if ps.videoTrackLocal == nil {
+		return nil, false
+	}
+	return ps.videoTrackLocal, true
+}
+
+func (ps *rtpPassthroughStream) VideoTrackLocalForRID(rid string) (webrtc.TrackLocal, bool) {
+	if rid != "" {
+		return nil, false
+	}
+	return ps.VideoTrackLocal()
+}
+
+func (ps *rtpPassthroughStream) AudioTrackLocal() (webrtc.TrackLocal, bool) {
+	if ps.audioTrackLocal == nil {
+		return nil, false
+	}
+	return ps.audioTrackLocal, true
+}
+
+func (ps *rtpPassthroughStream) switchVideoLayer(sender *webrtc.RTPSender, rid string) error {
+	return fmt.Errorf("no video layer with RID %q", rid)
+}
+
+func (ps *rtpPassthroughStream) setMuted(sender *webrtc.RTPSender, muted bool) error {
+	kind, err := muteSender(sender, muted)
+	if err != nil {
+		return err
+	}
+	if !muted && kind == webrtc.RTPCodecTypeVideo {
+		ps.RequestKeyFrame()
+	}
+	return nil
+}
+
+// handleRTCP records the given feedback, asks the source for a key frame when a viewer lost
+// video and, if it keeps a history of them, retransmits packets a viewer reports lost.
+func (ps *rtpPassthroughStream) handleRTCP(packets []rtcp.Packet) {
+	for _, event := range ps.rtcpFeedback.handle(packets) {
+		switch event := event.(type) {
+		case PictureLossEvent, FullIntraRequestEvent:
+			ps.RequestKeyFrame()
+		case NACKEvent:
+			if ps.videoTrackLocal == nil {
+				continue
+			}
+			sent, err := ps.videoTrackLocal.retransmit(event.SSRC, event.SequenceNumbers)
+			if err != nil {
+				ps.logger.Errorw("error retransmitting packets", "error", err)
+			}
+			if Debug {
+				ps.logger.Debugw("retransmitted packets", "requested", len(event.SequenceNumbers), "sent", sent)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package gostream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+func TestRTPPassthroughStream(t *testing.T) {
+	_, err := NewRTPPassthroughStream(RTPPassthroughStreamConfig{})
+	test.That(t, err, test.ShouldNotBeNil)
+
+	keyFrameRequests := make(chan time.Time, 10)
+	stream, err := NewRTPPassthroughStream(RTPPassthroughStreamConfig{
+		VideoCodec:        &webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+		NACKHistorySize:   DefaultNACKHistorySize,
+		OnKeyFrameRequest: func() { keyFrameRequests <- time.Now() },
+	})
+	test.That(t, err, test.ShouldBeNil)
+	passthrough := stream.(*rtpPassthroughStream)
+	passthrough.keyFrameRequestInterval = 100 * time.Millisecond
+	_, err = stream.InputVideoFrames(prop.Video{})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, stream.WriteAudioRTP(&rtp.Packet{}), test.ShouldNotBeNil)
+
+	trackLocal, ok := stream.VideoTrackLocal()
+	test.That(t, ok, test.ShouldBeTrue)
+	var first, second fakeTrackLocalWriter
+	_, err = trackLocal.Bind(&fakeTrackLocalContext{id: "first", ssrc: 1, writeStream: &first})
+	test.That(t, err, test.ShouldBeNil)
+	_, err = trackLocal.Bind(&fakeTrackLocalContext{id: "second", ssrc: 2, writeStream: &second})
+	test.That(t, err, test.ShouldBeNil)
+
+	for i := 0; i < 3; i++ {
+		packet := &rtp.Packet{Header: rtp.Header{
+			SSRC:           1234,
+			PayloadType:    100,
+			SequenceNumber: uint16(65534 + i),
+			Timestamp:      uint32(3000 * i),
+		}}
+		test.That(t, packet.Header.SetExtension(3, []byte{1}), test.ShouldBeNil)
+		test.That(t, stream.WriteVideoRTP(packet), test.ShouldBeNil)
+	}
+	// a restarted source continues the RTP streams the viewers receive.
+	test.That(t, stream.WriteVideoRTP(&rtp.Packet{Header: rtp.Header{SSRC: 5678, SequenceNumber: 10, Timestamp: 42}}), test.ShouldBeNil)
+
+	for _, tc := range []struct {
+		headers []rtp.Header
+		ssrc    uint32
+	}{
+		{first.headers, 1},
+		{second.headers, 2},
+	} {
+		test.That(t, tc.headers, test.ShouldHaveLength, 4)
+		for i, header := range tc.headers {
+			test.That(t, header.SSRC, test.ShouldEqual, tc.ssrc)
+			test.That(t, header.PayloadType, test.ShouldEqual, 96)
+			test.That(t, header.Extension, test.ShouldBeFalse)
+			if i == 0 {
+				continue
+			}
+			test.That(t, header.SequenceNumber, test.ShouldEqual, tc.headers[i-1].SequenceNumber+1)
+			if i < 3 {
+				test.That(t, header.Timestamp-tc.headers[i-1].Timestamp, test.ShouldEqual, 3000)
+			} else {
+				test.That(t, header.Timestamp-tc.headers[i-1].Timestamp, test.ShouldBeGreaterThan, 0)
+			}
+		}
+	}
+
+	// lost packets are retransmitted with the viewer's own sequence numbers.
+	stream.handleRTCP([]rtcp.Packet{
+		&rtcp.TransportLayerNack{MediaSSRC: 2, Nacks: rtcp.NackPairsFromSequenceNumbers([]uint16{second.headers[1].SequenceNumber})},
+		&rtcp.PictureLossIndication{MediaSSRC: 1},
+		&rtcp.PictureLossIndication{MediaSSRC: 2},
+	})
+	test.That(t, second.headers, test.ShouldHaveLength, 5)
+	test.That(t, second.headers[4], test.ShouldResemble, second.headers[1])
+	test.That(t, first.headers, test.ShouldHaveLength, 4)
+	// key frame requests are passed on to the source but not in quick succession. Those made
+	// too soon after the first are coalesced into one made once the interval has passed.
+	var requestedAt []time.Time
+	for len(requestedAt) < 2 {
+		select {
+		case at := <-keyFrameRequests:
+			requestedAt = append(requestedAt, at)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for key frame requests")
+		}
+	}
+	// the callbacks run on goroutines of their own so allow for when they were scheduled.
+	test.That(t, requestedAt[1].Sub(requestedAt[0]), test.ShouldBeGreaterThan, passthrough.keyFrameRequestInterval/2)
+
+	// a deferred request is dropped once the stream stops.
+	passthrough.keyFrameRequestInterval = time.Hour
+	stream.RequestKeyFrame()
+	passthrough.keyFrameMu.Lock()
+	timer := passthrough.keyFrameTimer
+	passthrough.keyFrameMu.Unlock()
+	test.That(t, timer, test.ShouldNotBeNil)
+	stream.Stop()
+	test.That(t, timer.Stop(), test.ShouldBeFalse)
+	test.That(t, keyFrameRequests, test.ShouldHaveLength, 0)
+
+	// a timer that fires just as Stop forgets it makes no request either.
+	passthrough.keyFrameRequestInterval = 10 * time.Millisecond
+	passthrough.keyFrameMu.Lock()
+	passthrough.lastKeyFrameRequest = time.Now()
+	passthrough.keyFrameMu.Unlock()
+	stream.RequestKeyFrame()
+	passthrough.keyFrameMu.Lock()
+	passthrough.keyFrameTimer = nil
+	passthrough.keyFrameMu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	test.That(t, keyFrameRequests, test.ShouldHaveLength, 0)
+}
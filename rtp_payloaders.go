@@ -0,0 +1,304 @@
+package gostream
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// AV1 OBU types that need handling when packetizing.
+const (
+	av1OBUTypeSequenceHeader    = 1
+	av1OBUTypeTemporalDelimiter = 2
+	av1OBUTypeTileList          = 8
+)
+
+// Bits of the aggregation header that starts every AV1 RTP payload.
+const (
+	av1AggregationHeaderZ = 0x80 // the first OBU continues one from the previous packet.
+	av1AggregationHeaderY = 0x40 // the last OBU continues in the next packet.
+	av1AggregationHeaderN = 0x08 // the packet starts a new coded video sequence.
+)
+
+// av1Payloader packetizes AV1 temporal units, as produced by encoders in the low overhead
+// bitstream format, according to the AV1 RTP payload format. Every OBU is sent without its
+// size field and prefixed with its length instead, with as many OBUs aggregated into a
+// packet as fit and OBUs too large for one packet fragmented across packets. Temporal
+// delimiters and tile lists are dropped as the format requires.
+type av1Payloader struct{}
+
+func (p *av1Payloader) Payload(mtu uint16, payload []byte) [][]byte {
+	obus, newCodedVideoSequence, err := av1OBUs(payload)
+	if err != nil || len(obus) == 0 {
+		return nil
+	}
+
+	var payloads [][]byte
+	packet := []byte{0}
+	for _, obu := range obus {
+		for len(obu) > 0 {
+			space := int(mtu) - len(packet)
+			size := space - leb128Size(space)
+			if size <= 0 {
+				// not even an empty packet has room.
+				if len(packet) == 1 {
+					return nil
+				}
+				payloads = append(payloads, packet)
+				packet = []byte{0}
+				continue
+			}
+			if size > len(obu) {
+				size = len(obu)
+			}
+			packet = appendLEB128(packet, size)
+			packet = append(packet, obu[:size]...)
+			obu = obu[size:]
+			if len(obu) > 0 {
+				packet[0] |= av1AggregationHeaderY
+				payloads = append(payloads, packet)
+				packet = []byte{av1AggregationHeaderZ}
+			}
+		}
+	}
+	if len(packet) > 1 {
+		payloads = append(payloads, packet)
+	}
+	if newCodedVideoSequence {
+		payloads[0][0] |= av1AggregationHeaderN
+	}
+	return payloads
+}
+
+var errInvalidAV1OBU = errors.New("invalid AV1 OBU")
+
+// av1OBUs splits the given temporal unit into the OBUs to send, without their size fields.
+// It also returns whether or not the temporal unit starts a new coded video sequence, which
+// is the case when it has a sequence header.
+func av1OBUs(data []byte) ([][]byte, bool, error) {
+	var obus [][]byte
+	var newCodedVideoSequence bool
+	for len(data) > 0 {
+		header := data[0]
+		headerSize := 1
+		// the extension flag adds a byte to the header.
+		if header&0x04 != 0 {
+			headerSize = 2
+		}
+		if len(data) < headerSize {
+			return nil, false, errInvalidAV1OBU
+		}
+		rest := data[headerSize:]
+		size := len(rest)
+		// without a size field the OBU takes up the rest of the data.
+		if header&0x02 != 0 {
+			var n int
+			var ok bool
+			size, n, ok = readLEB128(rest)
+			if !ok || size > len(rest)-n {
+				return nil, false, errInvalidAV1OBU
+			}
+			rest = rest[n:]
+		}
+
+		switch obuType := (header >> 3) & 0x0f; obuType {
+		case av1OBUTypeTemporalDelimiter, av1OBUTypeTileList:
+		default:
+			if obuType == av1OBUTypeSequenceHeader {
+				newCodedVideoSequence = true
+			}
+			obu := make([]byte, 0, headerSize+size)
+			obu = append(obu, header&^0x02)
+			obu = append(obu, data[1:headerSize]...)
+			obu = append(obu, rest[:size]...)
+			obus = append(obus, obu)
+		}
+		data = rest[size:]
+	}
+	return obus, newCodedVideoSequence, nil
+}
+
+// leb128Size returns how many bytes the given value takes up in LEB128 encoding.
+func leb128Size(value int) int {
+	size := 1
+	for value >= 0x80 {
+		value >>= 7
+		size++
+	}
+	return size
+}
+
+// appendLEB128 appends the LEB128 encoding of the given value.
+func appendLEB128(b []byte, value int) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+// readLEB128 reads a LEB128 encoded value of up to 8 bytes and returns it along with how
+// many bytes it took up.
+func readLEB128(b []byte) (int, int, bool) {
+	var value int
+	for i := 0; i < len(b) && i < 8; i++ {
+		value |= int(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return value, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// H265 NAL unit types that need handling when packetizing.
+const (
+	h265NALUTypeAccessUnitDelimiter = 35
+	h265NALUTypeFillerData          = 38
+	h265NALUTypeAggregationPacket   = 48
+	h265NALUTypeFragmentationUnit   = 49
+)
+
+const (
+	h265NALUHeaderSize = 2
+	h265FUHeaderSize   = 1
+	// h265AggregatedSizeSize is the size of the length preceding every NAL unit in an
+	// aggregation packet.
+	h265AggregatedSizeSize = 2
+)
+
+// h265Payloader packetizes H265 access units in Annex B format according to RFC 7798.
+// NAL units small enough, such as parameter sets, are combined into aggregation packets and
+// NAL units too large for one packet are split into fragmentation units. Access unit
+// delimiters and filler data are dropped. Decoding order numbers are not sent, so peers
+// must negotiate sprop-max-don-diff of 0, which is the default.
+type h265Payloader struct{}
+
+func (p *h265Payloader) Payload(mtu uint16, payload []byte) [][]byte {
+	var payloads [][]byte
+	var aggregated [][]byte
+	aggregatedSize := h265NALUHeaderSize
+	flush := func() {
+		switch len(aggregated) {
+		case 0:
+		case 1:
+			payloads = append(payloads, aggregated[0])
+		default:
+			payloads = append(payloads, h265AggregationPacket(aggregated, aggregatedSize))
+		}
+		aggregated = nil
+		aggregatedSize = h265NALUHeaderSize
+	}
+
+	for _, nalu := range annexBNALUs(payload) {
+		if len(nalu) < h265NALUHeaderSize {
+			continue
+		}
+		naluType := (nalu[0] >> 1) & 0x3f
+		if naluType == h265NALUTypeAccessUnitDelimiter || naluType == h265NALUTypeFillerData {
+			continue
+		}
+		if len(nalu) > int(mtu) {
+			flush()
+			payloads = append(payloads, h265FragmentationUnits(mtu, nalu)...)
+			continue
+		}
+		if len(aggregated) > 0 && aggregatedSize+h265AggregatedSizeSize+len(nalu) > int(mtu) {
+			flush()
+		}
+		aggregated = append(aggregated, nalu)
+		aggregatedSize += h265AggregatedSizeSize + len(nalu)
+	}
+	flush()
+	return payloads
+}
+
+// h265AggregationPacket returns an aggregation packet of the given size holding the given
+// NAL units. Its header has the forbidden bit set if any NAL unit has it, and the lowest
+// layer ID and temporal ID of the NAL units.
+func h265AggregationPacket(nalus [][]byte, size int) []byte {
+	var forbidden byte
+	layerID, temporalID := byte(0x3f), byte(0x07)
+	for _, nalu := range nalus {
+		forbidden |= nalu[0] & 0x80
+		if naluLayerID := (nalu[0]&0x01)<<5 | nalu[1]>>3; naluLayerID < layerID {
+			layerID = naluLayerID
+		}
+		if naluTemporalID := nalu[1] & 0x07; naluTemporalID < temporalID {
+			temporalID = naluTemporalID
+		}
+	}
+
+	packet := make([]byte, 0, size)
+	packet = append(packet,
+		forbidden|h265NALUTypeAggregationPacket<<1|layerID>>5,
+		layerID<<3|temporalID,
+	)
+	for _, nalu := range nalus {
+		packet = binary.BigEndian.AppendUint16(packet, uint16(len(nalu)))
+		packet = append(packet, nalu...)
+	}
+	return packet
+}
+
+// h265FragmentationUnits splits the given NAL unit into fragmentation units that fit the
+// given MTU.
+func h265FragmentationUnits(mtu uint16, nalu []byte) [][]byte {
+	maxFragmentSize := int(mtu) - h265NALUHeaderSize - h265FUHeaderSize
+	if maxFragmentSize <= 0 {
+		return nil
+	}
+	naluType := (nalu[0] >> 1) & 0x3f
+	// the payload header is the NAL unit's with the type replaced.
+	payloadHeader := []byte{nalu[0]&0x81 | h265NALUTypeFragmentationUnit<<1, nalu[1]}
+
+	var payloads [][]byte
+	data := nalu[h265NALUHeaderSize:]
+	for offset := 0; offset < len(data); offset += maxFragmentSize {
+		fragment := data[offset:]
+		if len(fragment) > maxFragmentSize {
+			fragment = fragment[:maxFragmentSize]
+		}
+		fuHeader := naluType
+		if offset == 0 {
+			fuHeader |= 0x80
+		}
+		if offset+len(fragment) == len(data) {
+			fuHeader |= 0x40
+		}
+		payload := make([]byte, 0, h265NALUHeaderSize+h265FUHeaderSize+len(fragment))
+		payload = append(payload, payloadHeader...)
+		payload = append(payload, fuHeader)
+		payload = append(payload, fragment...)
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+// annexBNALUs splits the given Annex B byte stream into its NAL units. Data without any
+// start code is taken to be a single NAL unit.
+func annexBNALUs(data []byte) [][]byte {
+	var nalus [][]byte
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			nalus = append(nalus, trimTrailingZeros(data[start:i]))
+		}
+		start = i + 3
+		i += 2
+	}
+	if start < 0 {
+		return [][]byte{data}
+	}
+	return append(nalus, trimTrailingZeros(data[start:]))
+}
+
+// trimTrailingZeros removes the zero bytes NAL units cannot end with, which belong to the
+// next start code or are trailing padding.
+func trimTrailingZeros(nalu []byte) []byte {
+	for len(nalu) > 0 && nalu[len(nalu)-1] == 0 {
+		nalu = nalu[:len(nalu)-1]
+	}
+	return nalu
+}
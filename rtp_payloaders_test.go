@@ -0,0 +1,96 @@
+package gostream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestAV1Payloader(t *testing.T) {
+	sequenceHeader := []byte{0x0a, 0x03, 1, 2, 3}
+	frame := bytes.Repeat([]byte{7}, 2500)
+	frameOBU := append(appendLEB128([]byte{0x32}, len(frame)), frame...)
+	// a temporal delimiter, the sequence header, a frame OBU and a last one without a size field.
+	temporalUnit := append([]byte{0x12, 0x00}, sequenceHeader...)
+	temporalUnit = append(temporalUnit, frameOBU...)
+	temporalUnit = append(temporalUnit, 0x30)
+	temporalUnit = append(temporalUnit, frame...)
+
+	payloads := (&av1Payloader{}).Payload(1200, temporalUnit)
+	test.That(t, len(payloads), test.ShouldBeGreaterThan, 1)
+	test.That(t, payloads[0][0]&av1AggregationHeaderN, test.ShouldNotEqual, 0)
+
+	// reassembling the OBU elements gives the OBUs without temporal delimiters or size fields.
+	var obus [][]byte
+	var partial []byte
+	for i, payload := range payloads {
+		test.That(t, len(payload), test.ShouldBeLessThanOrEqualTo, 1200)
+		test.That(t, payload[0]&av1AggregationHeaderZ != 0, test.ShouldEqual, i > 0 && payloads[i-1][0]&av1AggregationHeaderY != 0)
+		for data := payload[1:]; len(data) > 0; {
+			size, n, ok := readLEB128(data)
+			test.That(t, ok, test.ShouldBeTrue)
+			partial = append(partial, data[n:n+size]...)
+			data = data[n+size:]
+			if len(data) > 0 || payload[0]&av1AggregationHeaderY == 0 {
+				obus = append(obus, partial)
+				partial = nil
+			}
+		}
+	}
+	test.That(t, obus, test.ShouldResemble, [][]byte{
+		{0x08, 1, 2, 3},
+		append([]byte{0x30}, frame...),
+		append([]byte{0x30}, frame...),
+	})
+
+	_, n, ok := readLEB128(appendLEB128(nil, 300))
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, n, test.ShouldEqual, leb128Size(300))
+	test.That(t, (&av1Payloader{}).Payload(1200, []byte{0x32, 0x05, 1}), test.ShouldBeNil)
+}
+
+func TestH265Payloader(t *testing.T) {
+	aud := []byte{0x46, 0x01, 0x50}
+	vps := []byte{0x40, 0x01, 1, 2}
+	sps := []byte{0x42, 0x01, 3, 4, 5}
+	pps := []byte{0x44, 0x01, 6}
+	idr := append([]byte{0x26, 0x01}, bytes.Repeat([]byte{9}, 3000)...)
+	var accessUnit []byte
+	for _, nalu := range [][]byte{aud, vps, sps, pps, idr} {
+		accessUnit = append(accessUnit, 0, 0, 0, 1)
+		accessUnit = append(accessUnit, nalu...)
+	}
+
+	payloads := (&h265Payloader{}).Payload(1200, accessUnit)
+	test.That(t, payloads, test.ShouldHaveLength, 4)
+
+	// the parameter sets are aggregated and the access unit delimiter dropped.
+	aggregationPacket := payloads[0]
+	test.That(t, aggregationPacket[0]>>1&0x3f, test.ShouldEqual, h265NALUTypeAggregationPacket)
+	test.That(t, aggregationPacket[1], test.ShouldEqual, 0x01)
+	var aggregated [][]byte
+	for data := aggregationPacket[2:]; len(data) > 0; {
+		size := int(binary.BigEndian.Uint16(data))
+		aggregated = append(aggregated, data[2:2+size])
+		data = data[2+size:]
+	}
+	test.That(t, aggregated, test.ShouldResemble, [][]byte{vps, sps, pps})
+
+	// the slice is fragmented.
+	reassembled := []byte{idr[0], idr[1]}
+	for i, payload := range payloads[1:] {
+		test.That(t, len(payload), test.ShouldBeLessThanOrEqualTo, 1200)
+		test.That(t, payload[0]>>1&0x3f, test.ShouldEqual, h265NALUTypeFragmentationUnit)
+		test.That(t, payload[1], test.ShouldEqual, idr[1])
+		test.That(t, payload[2]&0x3f, test.ShouldEqual, 19)
+		test.That(t, payload[2]&0x80 != 0, test.ShouldEqual, i == 0)
+		test.That(t, payload[2]&0x40 != 0, test.ShouldEqual, i == 2)
+		reassembled = append(reassembled, payload[3:]...)
+	}
+	test.That(t, reassembled, test.ShouldResemble, idr)
+
+	// a lone small NAL unit is sent as is.
+	test.That(t, (&h265Payloader{}).Payload(1200, append([]byte{0, 0, 1}, pps...)), test.ShouldResemble, [][]byte{pps})
+}
@@ -0,0 +1,50 @@
+package gostream
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// rtpRewriter maps the sequence numbers and timestamps of relayed packets onto an RTP
+// stream of a binding's own. Every binding starts at random values like a new RTP stream
+// would, and keeps counting on from where it was when the relayed RTP stream changes its
+// SSRC, for instance when the source restarts.
+type rtpRewriter struct {
+	started         bool
+	sourceSSRC      uint32
+	sequenceOffset  uint16
+	timestampOffset uint32
+	lastSequence    uint16
+	lastTimestamp   uint32
+	lastWrite       time.Time
+}
+
+// rewrite returns the sequence number and timestamp the given relayed packet has on the
+// binding's RTP stream. The clock rate is used to advance the timestamp across a change
+// of source.
+func (r *rtpRewriter) rewrite(header *rtp.Header, clockRate uint32) (uint16, uint32) {
+	now := time.Now()
+	switch {
+	case !r.started:
+		//nolint:gosec
+		r.sequenceOffset = uint16(rand.Uint32()) - header.SequenceNumber
+		//nolint:gosec
+		r.timestampOffset = rand.Uint32() - header.Timestamp
+		r.started = true
+	case header.SSRC != r.sourceSSRC:
+		// the new source continues right after the last packet sent.
+		elapsed := uint32(now.Sub(r.lastWrite).Seconds() * float64(clockRate))
+		if elapsed == 0 {
+			elapsed = 1
+		}
+		r.sequenceOffset = r.lastSequence + 1 - header.SequenceNumber
+		r.timestampOffset = r.lastTimestamp + elapsed - header.Timestamp
+	}
+	r.sourceSSRC = header.SSRC
+	r.lastSequence = header.SequenceNumber + r.sequenceOffset
+	r.lastTimestamp = header.Timestamp + r.timestampOffset
+	r.lastWrite = now
+	return r.lastSequence, r.lastTimestamp
+}
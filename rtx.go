@@ -0,0 +1,107 @@
+package gostream
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// Retransmissions are sent as RTX (RFC 4588) to peers that negotiated it for the payload type of
+// the lost packet, and as the original packet otherwise. RTX packets carry the original sequence
+// number ahead of the original payload on an SSRC and with sequence numbers of their own so that
+// they do not disturb the loss statistics of the media they repair.
+const mimeTypeRTX = "video/rtx"
+
+// rtxEncoder turns packets sent to a single binding into RTX packets.
+type rtxEncoder struct {
+	ssrc uint32
+	// payloadTypes maps the payload types of media to the RTX payload types that repair them.
+	payloadTypes   map[uint8]uint8
+	sequenceNumber uint16
+}
+
+// negotiateRTX returns an rtxEncoder for a binding with the given SSRC if the given negotiated
+// codecs include RTX for any payload type.
+func negotiateRTX(negotiated []webrtc.RTPCodecParameters, ssrc webrtc.SSRC) *rtxEncoder {
+	payloadTypes := map[uint8]uint8{}
+	for _, c := range negotiated {
+		if !strings.EqualFold(c.MimeType, mimeTypeRTX) {
+			continue
+		}
+		if apt, ok := associatedPayloadType(c.SDPFmtpLine); ok {
+			payloadTypes[apt] = uint8(c.PayloadType)
+		}
+	}
+	if len(payloadTypes) == 0 {
+		return nil
+	}
+	//nolint:gosec
+	rtxSSRC := rand.Uint32()
+	for rtxSSRC == uint32(ssrc) {
+		//nolint:gosec
+		rtxSSRC = rand.Uint32()
+	}
+	return &rtxEncoder{
+		ssrc:         rtxSSRC,
+		payloadTypes: payloadTypes,
+		//nolint:gosec
+		sequenceNumber: uint16(rand.Uint32()),
+	}
+}
+
+// associatedPayloadType returns the payload type an RTX codec with the given format parameters
+// repairs.
+func associatedPayloadType(fmtpLine string) (uint8, bool) {
+	for _, param := range strings.Split(fmtpLine, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(key, "apt") {
+			continue
+		}
+		apt, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return 0, false
+		}
+		return uint8(apt), true
+	}
+	return 0, false
+}
+
+// encode returns the RTX packet that retransmits the given packet, or false if the peer did not
+// negotiate RTX for its payload type.
+func (e *rtxEncoder) encode(header *rtp.Header, payload []byte) (rtp.Header, []byte, bool) {
+	payloadType, ok := e.payloadTypes[header.PayloadType]
+	if !ok {
+		return rtp.Header{}, nil, false
+	}
+	rtxHeader := header.Clone()
+	rtxHeader.SSRC = e.ssrc
+	rtxHeader.PayloadType = payloadType
+	rtxHeader.SequenceNumber = e.sequenceNumber
+	rtxHeader.Padding = false
+	e.sequenceNumber++
+
+	rtxPayload := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(rtxPayload, header.SequenceNumber)
+	copy(rtxPayload[2:], payload)
+	return rtxHeader, rtxPayload, true
+}
+
+// withoutGenericNACK returns the given feedback without generic NACKs. Bindings that answer NACKs
+// from their own history bind with it so that the NACK responder pion registers by default,
+// which only handles streams with generic NACK feedback, leaves their packets alone rather
+// than retransmitting them a second time. Viewers still send NACKs since the feedback they
+// negotiated is unchanged.
+func withoutGenericNACK(feedback []webrtc.RTCPFeedback) []webrtc.RTCPFeedback {
+	filtered := make([]webrtc.RTCPFeedback, 0, len(feedback))
+	for _, fb := range feedback {
+		if fb.Type == webrtc.TypeRTCPFBNACK && fb.Parameter == "" {
+			continue
+		}
+		filtered = append(filtered, fb)
+	}
+	return filtered
+}
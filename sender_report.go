@@ -0,0 +1,66 @@
+package gostream
+
+import (
+	"context"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/utils"
+)
+
+// senderReportInterval is how often a viewer is sent a sender report for each track.
+const senderReportInterval = time.Second
+
+// ntpEpochOffset is the number of seconds in between the NTP epoch of 1900 and the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// ntpTime returns the given time as a 64 bit NTP timestamp.
+func ntpTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// senderReportTrack is a track that can report how its RTP timestamps relate to the time.
+type senderReportTrack interface {
+	senderReport(ssrc webrtc.SSRC, now time.Time) (*rtcp.SenderReport, bool)
+}
+
+// SendSenderReports periodically sends the peer of the given connection a sender report for
+// the stream track of the given sender until the context is done. The reports relate the
+// track's RTP timestamps to the stream's media clock, which every track of the stream shares,
+// so that the viewer can synchronize audio with video.
+//
+// It is meant for peer connections made without pion's report interceptor, which
+// webrtc.RegisterDefaultInterceptors registers, since the viewer would otherwise receive two
+// differing reports for the same track. That interceptor relates the timestamp of the latest
+// packet to when the packet was sent rather than captured, so its reports are off by however
+// long the media took to be encoded and sent, which differs in between audio and video. The
+// peer connections of a StreamServer are made with it and go.viam.com/utils/rpc offers no way
+// of leaving it out, so their viewers synchronize audio with video only that closely.
+func SendSenderReports(ctx context.Context, pc *webrtc.PeerConnection, sender *webrtc.RTPSender) {
+	ticker := time.NewTicker(senderReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		track, ok := sender.Track().(senderReportTrack)
+		if !ok {
+			continue
+		}
+		encodings := sender.GetParameters().Encodings
+		if len(encodings) == 0 {
+			continue
+		}
+		report, ok := track.senderReport(encodings[0].SSRC, time.Now())
+		if !ok {
+			continue
+		}
+		// errors only happen once the connection is closed, which also stops the sender.
+		utils.UncheckedError(pc.WriteRTCP([]rtcp.Packet{report}))
+	}
+}
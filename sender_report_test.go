@@ -0,0 +1,73 @@
+package gostream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+func TestNTPTime(t *testing.T) {
+	test.That(t, ntpTime(time.Unix(0, 0)), test.ShouldEqual, uint64(ntpEpochOffset)<<32)
+	test.That(t, ntpTime(time.Unix(1, int64(time.Second/2))), test.ShouldEqual, uint64(ntpEpochOffset+1)<<32|1<<31)
+}
+
+func TestSenderReportsAgree(t *testing.T) {
+	clock := newMediaClock()
+	video := newVideoTrackLocalStaticSample(
+		[]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}}, "video", "", "stream", clock, 0, 0)
+	audio := newAudioTrackLocalStaticSample(
+		[]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000}}, "audio", "stream", clock)
+	audio.setAudioLatency(20 * time.Millisecond)
+
+	var videoWriter, audioWriter fakeTrackLocalWriter
+	_, err := video.Bind(&fakeTrackLocalContext{id: "video", ssrc: 1, writeStream: &videoWriter})
+	test.That(t, err, test.ShouldBeNil)
+	_, err = audio.Bind(&fakeTrackLocalContext{
+		id:          "audio",
+		ssrc:        2,
+		writeStream: &audioWriter,
+		codecs: []webrtc.RTPCodecParameters{{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000},
+			PayloadType:        111,
+		}},
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	_, ok := video.senderReport(3, time.Now())
+	test.That(t, ok, test.ShouldBeFalse)
+
+	// the video takes longer to be encoded than the audio captured 40ms after it, so the two
+	// are written in the opposite order of their capture.
+	videoCapturedAt := time.Now().Add(-time.Second)
+	audioCapturedAt := videoCapturedAt.Add(40 * time.Millisecond)
+	test.That(t, audio.WriteData(0, []byte{1, 2, 3}, 0, audioCapturedAt), test.ShouldBeNil)
+	test.That(t, video.WriteData(0, []byte{1, 2, 3}, 0, videoCapturedAt), test.ShouldBeNil)
+	test.That(t, videoWriter.headers, test.ShouldHaveLength, 1)
+	test.That(t, audioWriter.headers, test.ShouldHaveLength, 1)
+
+	// reports taken at different times map the timestamps of both tracks to the NTP times the
+	// media was captured at.
+	videoReport, ok := video.senderReport(1, time.Now())
+	test.That(t, ok, test.ShouldBeTrue)
+	audioReport, ok := audio.senderReport(2, time.Now().Add(300*time.Millisecond))
+	test.That(t, ok, test.ShouldBeTrue)
+
+	ntpOfTimestamp := func(report *rtcp.SenderReport, timestamp, clockRate uint32) time.Time {
+		seconds := int64(report.NTPTime>>32) - ntpEpochOffset
+		fraction := int64(report.NTPTime&0xffffffff) * int64(time.Second) >> 32
+		reportedAt := time.Unix(seconds, fraction)
+		return reportedAt.Add(-time.Duration(report.RTPTime-timestamp) * time.Second / time.Duration(clockRate))
+	}
+	videoAt := ntpOfTimestamp(videoReport, videoWriter.headers[0].Timestamp, 90000)
+	audioAt := ntpOfTimestamp(audioReport, audioWriter.headers[0].Timestamp, 48000)
+	test.That(t, audioAt.Sub(videoAt), test.ShouldAlmostEqual, 40*time.Millisecond, time.Millisecond)
+	test.That(t, videoAt.Sub(videoCapturedAt), test.ShouldAlmostEqual, 0, time.Millisecond)
+
+	for _, report := range []*rtcp.SenderReport{videoReport, audioReport} {
+		test.That(t, report.PacketCount, test.ShouldEqual, 1)
+		test.That(t, report.OctetCount, test.ShouldBeGreaterThan, 0)
+	}
+}
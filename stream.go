@@ -4,17 +4,24 @@ package gostream
 import (
 	"context"
 	"errors"
+	"fmt"
 	"image"
+	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/disintegration/imaging"
 	"github.com/edaniels/golog"
 	"github.com/google/uuid"
 	// register screen drivers.
 	_ "github.com/pion/mediadevices/pkg/driver/microphone"
 	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
+	"go.uber.org/multierr"
 	"go.viam.com/utils"
 
 	"github.com/edaniels/gostream/codec"
@@ -37,15 +44,61 @@ type Stream interface {
 
 	InputVideoFrames(props prop.Video) (chan<- MediaReleasePair[image.Image], error)
 
+	// InputAudioChunks returns the channel to send audio to. Chunks may be of any size; they are
+	// encoded in frames as long as the latency of the given properties, or 20ms if it is not set.
 	InputAudioChunks(props prop.Audio) (chan<- MediaReleasePair[wave.Audio], error)
 
+	// SetVideoBitrate changes the target bitrate, in bits per second, of the video encoder
+	// while streaming. It also applies to encoders created later on (e.g. on a resolution change).
+	SetVideoBitrate(bitrate int) error
+
+	// SetAudioBitrate changes the target bitrate, in bits per second, of the audio encoder
+	// while streaming. It also applies to encoders created later on.
+	SetAudioBitrate(bitrate int) error
+
+	// RequestKeyFrame asks the video encoder to produce a key frame as soon as possible.
+	// Requests made in quick succession are coalesced into a single key frame.
+	RequestKeyFrame()
+
+	// SetMaxTemporalLayer limits the video sent to the peer whose video RTP stream has the
+	// given SSRC to temporal layers up to and including the given one, starting at the next
+	// base layer frame. A negative layer sends all layers. This only has an effect when the
+	// video encoder produces temporal layers (see codec.VideoEncoderOptions.ScalabilityMode).
+	SetMaxTemporalLayer(ssrc webrtc.SSRC, layer int) error
+
+	// AddRTCPEventHandler calls the given handler with every piece of RTCP feedback viewers
+	// send about the stream's media until the returned function is called. Handlers are
+	// called from the goroutine reading RTCP and must not block.
+	AddRTCPEventHandler(handler func(RTCPEvent)) (remove func())
+
+	// RTCPStats returns a snapshot of the RTCP feedback viewers have sent about the stream's media.
+	RTCPStats() RTCPStats
+
+	// VideoFrameStats returns how many video frames the stream has received, dropped and
+	// encoded since it was created.
+	VideoFrameStats() VideoFrameStats
+
 	// Stop stops further processing of frames.
 	Stop()
 }
 
 type internalStream interface {
+	// VideoTrackLocal returns the video track of the first video layer.
 	VideoTrackLocal() (webrtc.TrackLocal, bool)
+	// VideoTrackLocalForRID returns the video track of the simulcast layer with the given RID.
+	VideoTrackLocalForRID(rid string) (webrtc.TrackLocal, bool)
 	AudioTrackLocal() (webrtc.TrackLocal, bool)
+
+	// switchVideoLayer switches the peer sent video by the given sender to the layer with
+	// the given RID.
+	switchVideoLayer(sender *webrtc.RTPSender, rid string) error
+
+	// setMuted mutes or unmutes the track of the given sender for its peer alone. A muted
+	// track stays negotiated but is sent nothing.
+	setMuted(sender *webrtc.RTPSender, muted bool) error
+
+	// handleRTCP handles RTCP sent by a viewer of the stream.
+	handleRTCP(packets []rtcp.Packet)
 }
 
 // MediaReleasePair associates a media with a corresponding
@@ -63,9 +116,41 @@ func NewStream(config StreamConfig) (Stream, error) {
 	if logger == nil {
 		logger = golog.Global()
 	}
-	if config.VideoEncoderFactory == nil && config.AudioEncoderFactory == nil {
+
+	var videoFactories []codec.VideoEncoderFactory
+	var audioFactories []codec.AudioEncoderFactory
+	if config.VideoEncoderFactory != nil {
+		videoFactories = append(videoFactories, config.VideoEncoderFactory)
+	}
+	if config.AudioEncoderFactory != nil {
+		audioFactories = append(audioFactories, config.AudioEncoderFactory)
+	}
+	if config.Registry != nil {
+		videoFactories = appendNewFactories(videoFactories, config.Registry.VideoEncoderFactories())
+		audioFactories = appendNewFactories(audioFactories, config.Registry.AudioEncoderFactories())
+	}
+	if len(videoFactories) == 0 && len(audioFactories) == 0 {
 		return nil, errors.New("at least one audio or video encoder factory must be set")
 	}
+	if len(config.Simulcast) != 0 {
+		if len(videoFactories) == 0 {
+			return nil, errors.New("simulcast requires a video encoder factory")
+		}
+		if err := validateSimulcastLayers(config.Simulcast); err != nil {
+			return nil, err
+		}
+	}
+	if cc := config.CongestionControl; cc != nil {
+		if len(videoFactories) == 0 {
+			return nil, errors.New("congestion control requires a video encoder factory")
+		}
+		if cc.MinBitrate < 0 || cc.MaxBitrate < 0 || (cc.MaxBitrate != 0 && cc.MinBitrate > cc.MaxBitrate) {
+			return nil, fmt.Errorf("invalid congestion control bitrates %d to %d", cc.MinBitrate, cc.MaxBitrate)
+		}
+	}
+	if config.FECOverhead < 0 || config.FECOverhead > 1 {
+		return nil, fmt.Errorf("forward error correction overhead must be between 0 and 1, not %v", config.FECOverhead)
+	}
 	if config.TargetFrameRate == 0 {
 		config.TargetFrameRate = codec.DefaultKeyFrameInterval
 	}
@@ -75,21 +160,51 @@ func NewStream(config StreamConfig) (Stream, error) {
 		name = uuid.NewString()
 	}
 
-	var trackLocal *trackLocalStaticSample
-	if config.VideoEncoderFactory != nil {
-		trackLocal = newVideoTrackLocalStaticSample(
-			webrtc.RTPCodecCapability{MimeType: config.VideoEncoderFactory.MIMEType()},
-			"video",
-			name,
-		)
+	// audio and video share a clock so that viewers can synchronize them.
+	clock := newMediaClock()
+
+	var videoLayers []*videoLayer
+	if len(videoFactories) != 0 {
+		layers := config.Simulcast
+		if len(layers) == 0 {
+			layers = []SimulcastLayer{{}}
+		}
+		for _, layer := range layers {
+			var congestion *congestionController
+			if config.CongestionControl != nil {
+				// a layer with a bitrate of its own is never sent more.
+				cc := *config.CongestionControl
+				if layer.TargetBitrate != 0 && (cc.MaxBitrate == 0 || layer.TargetBitrate < cc.MaxBitrate) {
+					cc.MaxBitrate = layer.TargetBitrate
+				}
+				congestion = newCongestionController(cc, config.VideoEncoderOptions.TargetBitrate)
+			}
+			videoLayers = append(videoLayers, &videoLayer{
+				rid:           layer.RID,
+				scale:         layer.ScaleResolutionDownBy,
+				targetBitrate: layer.TargetBitrate,
+				track: newVideoTrackLocalStaticSample(
+					codecCapabilities(videoFactories),
+					"video",
+					layer.RID,
+					name,
+					clock,
+					config.NACKHistorySize,
+					config.FECOverhead,
+				),
+				encoders:   make([]codec.VideoEncoder, len(videoFactories)),
+				congestion: congestion,
+			})
+		}
 	}
 
 	var audioTrackLocal *trackLocalStaticSample
-	if config.AudioEncoderFactory != nil {
+	if len(audioFactories) != 0 {
 		audioTrackLocal = newAudioTrackLocalStaticSample(
-			webrtc.RTPCodecCapability{MimeType: config.AudioEncoderFactory.MIMEType()},
+			codecCapabilities(audioFactories),
 			"audio",
 			name,
+			clock,
 		)
 	}
 
@@ -99,22 +214,181 @@ func NewStream(config StreamConfig) (Stream, error) {
 		config:           config,
 		streamingReadyCh: make(chan struct{}),
 
-		videoTrackLocal: trackLocal,
+		videoFactories:  videoFactories,
+		videoLayers:     videoLayers,
 		inputImageChan:  make(chan MediaReleasePair[image.Image]),
-		outputVideoChan: make(chan []byte),
+		encodeVideoChan: make(chan capturedFrame, videoEncodeQueueSize),
+		outputVideoChan: make(chan encodedData, videoSendQueueSize),
 
+		audioFactories:  audioFactories,
+		audioEncoders:   make([]codec.AudioEncoder, len(audioFactories)),
 		audioTrackLocal: audioTrackLocal,
 		inputAudioChan:  make(chan MediaReleasePair[wave.Audio]),
-		outputAudioChan: make(chan []byte),
+		outputAudioChan: make(chan encodedData),
+
+		rtcpFeedback: newRTCPFeedback(),
 
 		logger:            logger,
 		shutdownCtx:       ctx,
 		shutdownCtxCancel: cancelFunc,
 	}
+	// viewers joining a running stream would otherwise wait for the next scheduled key frame.
+	for _, layer := range videoLayers {
+		layer.track.rtpTrack.onBind = bs.RequestKeyFrame
+		layer.track.rtpTrack.onUnbind = bs.viewerUnbound
+	}
+	if audioTrackLocal != nil {
+		audioTrackLocal.rtpTrack.onUnbind = bs.viewerUnbound
+	}
 
 	return bs, nil
 }
 
+// appendNewFactories appends the factories whose MIME type is not already present.
+func appendNewFactories[T interface{ MIMEType() string }](factories, toAdd []T) []T {
+	for _, factory := range toAdd {
+		var present bool
+		for _, existing := range factories {
+			if strings.EqualFold(existing.MIMEType(), factory.MIMEType()) {
+				present = true
+				break
+			}
+		}
+		if !present {
+			factories = append(factories, factory)
+		}
+	}
+	return factories
+}
+
+// codecCapabilities returns the RTP codec capabilities the given factories produce.
+func codecCapabilities[T interface{ MIMEType() string }](factories []T) []webrtc.RTPCodecCapability {
+	capabilities := make([]webrtc.RTPCodecCapability, 0, len(factories))
+	for _, factory := range factories {
+		capabilities = append(capabilities, webrtc.RTPCodecCapability{MimeType: factory.MIMEType()})
+	}
+	return capabilities
+}
+
+// validateSimulcastLayers checks that the given layers can be told apart by peers and have
+// a sensible resolution and bitrate.
+func validateSimulcastLayers(layers []SimulcastLayer) error {
+	rids := make(map[string]struct{}, len(layers))
+	for _, layer := range layers {
+		if layer.RID == "" {
+			return errors.New("simulcast layers must have a RID")
+		}
+		if _, ok := rids[layer.RID]; ok {
+			return fmt.Errorf("duplicate simulcast layer RID %q", layer.RID)
+		}
+		rids[layer.RID] = struct{}{}
+		if layer.ScaleResolutionDownBy != 0 && layer.ScaleResolutionDownBy < 1 {
+			return fmt.Errorf("simulcast layer %q cannot scale the resolution up", layer.RID)
+		}
+		if layer.TargetBitrate < 0 {
+			return fmt.Errorf("invalid bitrate %d for simulcast layer %q", layer.TargetBitrate, layer.RID)
+		}
+	}
+	return nil
+}
+
+// A videoLayer is one encoding of a stream's video sent on its own track. A stream without
+// simulcast has a single layer at the source's resolution.
+type videoLayer struct {
+	rid   string
+	scale float64
+	// targetBitrate overrides the configured bitrate if set.
+	targetBitrate int
+	track         *trackLocalStaticSample
+	// encoders has an encoder for each of the stream's video codecs that exists while any
+	// peer receives the layer with that codec.
+	encoders []codec.VideoEncoder
+
+	// congestion estimates the bandwidth of the layer's viewers if congestion control is
+	// enabled. bitrateLimit is its latest estimate, guarded by the stream's encoderMu, and
+	// congestionLevel is how far the layer's video is degraded.
+	congestion      *congestionController
+	bitrateLimit    int
+	congestionLevel atomic.Int32
+	// resolutionScale and framesSeen are only used by the encode stage to adapt the layer's
+	// resolution and frame rate to congestion.
+	resolutionScale int
+	framesSeen      int
+}
+
+// hasViewer returns whether or not the layer is sent to a viewer on the given SSRC.
+func (l *videoLayer) hasViewer(ssrc webrtc.SSRC) bool {
+	_, ok := l.track.bindingStats(ssrc)
+	return ok
+}
+
+// scaled returns the given frame at the layer's resolution further divided by the given scale.
+func (l *videoLayer) scaled(img image.Image, scale float64) image.Image {
+	scale *= math.Max(l.scale, 1)
+	if scale <= 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	return imaging.Resize(img, scaledDimension(bounds.Dx(), scale), scaledDimension(bounds.Dy(), scale), imaging.Linear)
+}
+
+// scaledDimension divides the given dimension by the given scale. The result is kept even
+// since encoders subsample chroma by two.
+func scaledDimension(dimension int, scale float64) int {
+	scaled := int(float64(dimension)/scale) &^ 1
+	if scaled < 2 {
+		return 2
+	}
+	return scaled
+}
+
+// The video pipeline's queues are kept short since anything queued adds latency.
+const (
+	// videoEncodeQueueSize is how many captured frames may wait on the encoder.
+	videoEncodeQueueSize = 1
+	// videoSendQueueSize is how many encoded frames may wait to be sent.
+	videoSendQueueSize = 2
+)
+
+// VideoFrameStats counts what happened to the video frames given to a stream.
+type VideoFrameStats struct {
+	// FramesReceived is how many frames were received from the video source.
+	FramesReceived uint64
+	// FramesDroppedForFrameRate is how many frames were dropped for arriving faster than the
+	// target frame rate.
+	FramesDroppedForFrameRate uint64
+	// FramesDroppedForBacklog is how many frames were dropped because the encoder was still
+	// busy with earlier frames.
+	FramesDroppedForBacklog uint64
+	// FramesEncoded is how many frames were encoded.
+	FramesEncoded uint64
+}
+
+type videoFrameCounters struct {
+	framesReceived            atomic.Uint64
+	framesDroppedForFrameRate atomic.Uint64
+	framesDroppedForBacklog   atomic.Uint64
+	framesEncoded             atomic.Uint64
+}
+
+// capturedFrame is a frame queued for encoding along with when it was captured.
+type capturedFrame struct {
+	MediaReleasePair[image.Image]
+	capturedAt time.Time
+}
+
+// encodedData is media encoded with the codec of the given index. Media that is
+// not layered is always in the base temporal layer. Video also has the index of the
+// video layer it was encoded for. mediaTime is when the media was captured, which its
+// timestamps are derived from.
+type encodedData struct {
+	layerIdx      int
+	codecIdx      int
+	data          []byte
+	temporalLayer int
+	mediaTime     time.Time
+}
+
 type basicStream struct {
 	mu               sync.RWMutex
 	name             string
@@ -122,15 +396,30 @@ type basicStream struct {
 	started          bool
 	streamingReadyCh chan struct{}
 
-	videoTrackLocal *trackLocalStaticSample
+	// videoFactories and audioFactories are the codecs offered to peers in order of preference.
+	// Each has a corresponding encoder, one per video layer for video, that exists while
+	// any peer uses the codec.
+	videoFactories  []codec.VideoEncoderFactory
+	videoLayers     []*videoLayer
 	inputImageChan  chan MediaReleasePair[image.Image]
-	outputVideoChan chan []byte
-	videoEncoder    codec.VideoEncoder
+	encodeVideoChan chan capturedFrame
+	outputVideoChan chan encodedData
+	videoStats      videoFrameCounters
 
+	audioFactories  []codec.AudioEncoderFactory
+	audioEncoders   []codec.AudioEncoder
 	audioTrackLocal *trackLocalStaticSample
 	inputAudioChan  chan MediaReleasePair[wave.Audio]
-	outputAudioChan chan []byte
-	audioEncoder    codec.AudioEncoder
+	outputAudioChan chan encodedData
+
+	// encoderMu guards the encoders and their options against changes made while
+	// streaming. The encoders are only replaced by the processing goroutines.
+	encoderMu sync.Mutex
+
+	// keyFrameRequested is set when a key frame has been requested but not yet forced.
+	keyFrameRequested atomic.Bool
+
+	rtcpFeedback *rtcpFeedback
 
 	// audioLatency specifies how long in between audio samples. This must be guaranteed
 	// by all streamed audio.
@@ -156,8 +445,9 @@ func (bs *basicStream) Start() {
 	}
 	bs.started = true
 	close(bs.streamingReadyCh)
-	bs.activeBackgroundWorkers.Add(4)
+	bs.activeBackgroundWorkers.Add(5)
 	utils.ManagedGo(bs.processInputFrames, bs.activeBackgroundWorkers.Done)
+	utils.ManagedGo(bs.encodeFrames, bs.activeBackgroundWorkers.Done)
 	utils.ManagedGo(bs.processOutputFrames, bs.activeBackgroundWorkers.Done)
 	utils.ManagedGo(bs.processInputAudioChunks, bs.activeBackgroundWorkers.Done)
 	utils.ManagedGo(bs.processOutputAudioChunks, bs.activeBackgroundWorkers.Done)
@@ -174,13 +464,15 @@ func (bs *basicStream) Stop() {
 	bs.started = false
 	bs.shutdownCtxCancel()
 	bs.activeBackgroundWorkers.Wait()
-	if bs.audioEncoder != nil {
-		bs.audioEncoder.Close()
+	bs.resetVideoEncoders()
+	for codecIdx := range bs.audioEncoders {
+		bs.resetAudioEncoder(codecIdx)
 	}
 
 	// reset
-	bs.outputVideoChan = make(chan []byte)
-	bs.outputAudioChan = make(chan []byte)
+	bs.encodeVideoChan = make(chan capturedFrame, videoEncodeQueueSize)
+	bs.outputVideoChan = make(chan encodedData, videoSendQueueSize)
+	bs.outputAudioChan = make(chan encodedData)
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	bs.shutdownCtx = ctx
 	bs.shutdownCtxCancel = cancelFunc
@@ -194,102 +486,536 @@ func (bs *basicStream) StreamingReady() (<-chan struct{}, context.Context) {
 }
 
 func (bs *basicStream) InputVideoFrames(props prop.Video) (chan<- MediaReleasePair[image.Image], error) {
-	if bs.config.VideoEncoderFactory == nil {
+	if len(bs.videoFactories) == 0 {
 		return nil, errors.New("no video in stream")
 	}
 	return bs.inputImageChan, nil
 }
 
 func (bs *basicStream) InputAudioChunks(props prop.Audio) (chan<- MediaReleasePair[wave.Audio], error) {
-	if bs.config.AudioEncoderFactory == nil {
+	if len(bs.audioFactories) == 0 {
 		return nil, errors.New("no audio in stream")
 	}
+	latency := props.Latency
+	if latency == 0 {
+		latency = AudioFrameDuration20ms
+	}
 	bs.mu.Lock()
-	if bs.audioLatencySet && bs.audioLatency != props.Latency {
+	defer bs.mu.Unlock()
+	if bs.audioLatencySet && bs.audioLatency != latency {
 		return nil, errors.New("cannot stream audio source with different latencies")
 	}
 	bs.audioLatencySet = true
-	bs.audioLatency = props.Latency
-	bs.mu.Unlock()
+	bs.audioLatency = latency
 	return bs.inputAudioChan, nil
 }
 
+var errBitrateUnsupported = errors.New("encoder does not support changing its bitrate")
+
+func (bs *basicStream) SetVideoBitrate(bitrate int) error {
+	if len(bs.videoFactories) == 0 {
+		return errors.New("no video in stream")
+	}
+	if bitrate <= 0 {
+		return fmt.Errorf("invalid bitrate %d", bitrate)
+	}
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	// layers with a bitrate of their own keep it.
+	for _, layer := range bs.videoLayers {
+		if layer.targetBitrate != 0 {
+			continue
+		}
+		effectiveBitrate := bitrate
+		if layer.bitrateLimit != 0 && layer.bitrateLimit < bitrate {
+			effectiveBitrate = layer.bitrateLimit
+		}
+		if err := setEncoderBitrates(layer.encoders, effectiveBitrate); err != nil {
+			return err
+		}
+	}
+	bs.config.VideoEncoderOptions.TargetBitrate = bitrate
+	return nil
+}
+
+// layerBitrate returns the bitrate the encoders of the given layer should use, or zero for the
+// encoder's default. It must be called with encoderMu held.
+func (bs *basicStream) layerBitrate(layer *videoLayer) int {
+	bitrate := layer.targetBitrate
+	if bitrate == 0 {
+		bitrate = bs.config.VideoEncoderOptions.TargetBitrate
+	}
+	if layer.bitrateLimit != 0 && (bitrate == 0 || layer.bitrateLimit < bitrate) {
+		return layer.bitrateLimit
+	}
+	return bitrate
+}
+
+// adaptToCongestion updates the bandwidth estimate of each layer with the given feedback and
+// adapts the layer's video to it. Each layer only follows the viewers it is sent to so that a
+// viewer on a congested link does not degrade the layers of the others.
+func (bs *basicStream) adaptToCongestion(event RTCPEvent) {
+	for _, layer := range bs.videoLayers {
+		bitrate, level, changed := layer.congestion.handle(event, layer.hasViewer)
+		if !changed {
+			continue
+		}
+		if Debug {
+			bs.logger.Debugw("adapting video to congestion", "rid", layer.rid, "bitrate", bitrate, "level", level)
+		}
+		layer.congestionLevel.Store(int32(level))
+
+		bs.encoderMu.Lock()
+		layer.bitrateLimit = bitrate
+		if err := setEncoderBitrates(layer.encoders, bs.layerBitrate(layer)); err != nil {
+			// the encoders pick up the new bitrate when they are next made.
+			if Debug {
+				bs.logger.Debugw("error adapting video bitrate", "rid", layer.rid, "error", err)
+			}
+		}
+		bs.encoderMu.Unlock()
+	}
+}
+
+// congestionScale returns how much the resolution and the frame rate of the given layer are
+// currently divided by to adapt to congestion.
+func (bs *basicStream) congestionScale(layer *videoLayer) (resolution, frameRate int) {
+	resolution, frameRate = 1, 1
+	if layer.congestion == nil {
+		return
+	}
+	scale := 1 << layer.congestionLevel.Load()
+	if bs.config.CongestionControl.AdaptResolution {
+		resolution = scale
+	}
+	if bs.config.CongestionControl.AdaptFrameRate {
+		frameRate = scale
+	}
+	return
+}
+
+func (bs *basicStream) SetAudioBitrate(bitrate int) error {
+	if len(bs.audioFactories) == 0 {
+		return errors.New("no audio in stream")
+	}
+	if bitrate <= 0 {
+		return fmt.Errorf("invalid bitrate %d", bitrate)
+	}
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	if err := setEncoderBitrates(bs.audioEncoders, bitrate); err != nil {
+		return err
+	}
+	bs.config.AudioEncoderOptions.TargetBitrate = bitrate
+	return nil
+}
+
+// setEncoderBitrates sets the bitrate of all running encoders. No bitrate is changed
+// unless all of them support it.
+func setEncoderBitrates[T any](encoders []T, bitrate int) error {
+	var controllers []codec.BitrateController
+	for _, encoder := range encoders {
+		if any(encoder) == nil {
+			continue
+		}
+		controller, ok := any(encoder).(codec.BitrateController)
+		if !ok {
+			return errBitrateUnsupported
+		}
+		controllers = append(controllers, controller)
+	}
+	for _, controller := range controllers {
+		if err := controller.SetBitrate(bitrate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minKeyFrameRequestInterval is the minimum time between key frames forced on request so that
+// many viewers reporting the same loss do not flood the stream with key frames.
+const minKeyFrameRequestInterval = 500 * time.Millisecond
+
+func (bs *basicStream) RequestKeyFrame() {
+	bs.keyFrameRequested.Store(true)
+}
+
+// forceKeyFrame asks the given video encoder for a key frame.
+func (bs *basicStream) forceKeyFrame(encoder codec.VideoEncoder) {
+	controller, ok := encoder.(codec.KeyFrameController)
+	if !ok {
+		if Debug {
+			bs.logger.Debug("video encoder cannot force key frames")
+		}
+		return
+	}
+	if err := controller.ForceKeyFrame(); err != nil {
+		bs.logger.Errorw("error forcing key frame", "error", err)
+	}
+}
+
+func (bs *basicStream) SetMaxTemporalLayer(ssrc webrtc.SSRC, layer int) error {
+	if len(bs.videoLayers) == 0 {
+		return errors.New("no video in stream")
+	}
+	var err error
+	for _, videoLayer := range bs.videoLayers {
+		// a peer is bound to the track of a single layer at a time.
+		if err = videoLayer.track.setMaxTemporalLayer(ssrc, layer); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (bs *basicStream) AddRTCPEventHandler(handler func(RTCPEvent)) func() {
+	return bs.rtcpFeedback.addHandler(handler)
+}
+
+func (bs *basicStream) RTCPStats() RTCPStats {
+	return bs.rtcpFeedback.snapshot()
+}
+
+// handleRTCP records the given feedback, asks for a key frame when a viewer lost video
+// and, if it keeps a history of them, retransmits packets a viewer reports lost.
+func (bs *basicStream) handleRTCP(packets []rtcp.Packet) {
+	for _, event := range bs.rtcpFeedback.handle(packets) {
+		switch event := event.(type) {
+		case ReceiverReportEvent, REMBEvent:
+			if bs.config.CongestionControl != nil {
+				bs.adaptToCongestion(event)
+			}
+		case PictureLossEvent, FullIntraRequestEvent:
+			bs.RequestKeyFrame()
+		case NACKEvent:
+			var sent int
+			var err error
+			for _, layer := range bs.videoLayers {
+				layerSent, layerErr := layer.track.retransmit(event.SSRC, event.SequenceNumbers)
+				sent += layerSent
+				err = multierr.Combine(err, layerErr)
+			}
+			if err != nil {
+				bs.logger.Errorw("error retransmitting packets", "error", err)
+			}
+			if Debug {
+				bs.logger.Debugw("retransmitted packets", "requested", len(event.SequenceNumbers), "sent", sent)
+			}
+		}
+	}
+}
+
+func (bs *basicStream) VideoFrameStats() VideoFrameStats {
+	return VideoFrameStats{
+		FramesReceived:            bs.videoStats.framesReceived.Load(),
+		FramesDroppedForFrameRate: bs.videoStats.framesDroppedForFrameRate.Load(),
+		FramesDroppedForBacklog:   bs.videoStats.framesDroppedForBacklog.Load(),
+		FramesEncoded:             bs.videoStats.framesEncoded.Load(),
+	}
+}
+
 func (bs *basicStream) VideoTrackLocal() (webrtc.TrackLocal, bool) {
-	return bs.videoTrackLocal, bs.videoTrackLocal != nil
+	if len(bs.videoLayers) == 0 {
+		return nil, false
+	}
+	return bs.videoLayers[0].track, true
+}
+
+func (bs *basicStream) VideoTrackLocalForRID(rid string) (webrtc.TrackLocal, bool) {
+	layer := bs.videoLayerForRID(rid)
+	if layer == nil {
+		return nil, false
+	}
+	return layer.track, true
+}
+
+// videoLayerForRID returns the video layer with the given RID, if any.
+func (bs *basicStream) videoLayerForRID(rid string) *videoLayer {
+	for _, layer := range bs.videoLayers {
+		if layer.rid == rid {
+			return layer
+		}
+	}
+	return nil
+}
+
+// switchVideoLayer replaces the track of the given sender with the track of the layer with
+// the given RID. The new track continues the RTP stream the peer was receiving so that the
+// switch looks like a resolution change rather than a new stream to the peer.
+// viewerUnbound forgets the RTCP feedback about the given SSRC once no track of the stream
+// sends to it anymore. A viewer switching video layers keeps its SSRC and so its feedback.
+func (bs *basicStream) viewerUnbound(ssrc webrtc.SSRC) {
+	for _, layer := range bs.videoLayers {
+		if layer.track.expectsHandoff(ssrc) {
+			return
+		}
+	}
+	bs.rtcpFeedback.forget(ssrc)
+}
+
+func (bs *basicStream) switchVideoLayer(sender *webrtc.RTPSender, rid string) error {
+	to := bs.videoLayerForRID(rid)
+	if to == nil {
+		return fmt.Errorf("no video layer with RID %q", rid)
+	}
+	var from *videoLayer
+	for _, layer := range bs.videoLayers {
+		if sender.Track() == layer.track {
+			from = layer
+			break
+		}
+	}
+	if from == nil {
+		return errors.New("sender is not sending the stream's video")
+	}
+	if from == to {
+		return nil
+	}
+
+	encodings := sender.GetParameters().Encodings
+	if len(encodings) == 0 {
+		return errors.New("sender has no encodings")
+	}
+	ssrc := encodings[0].SSRC
+	if handoff, ok := from.track.handoff(ssrc); ok {
+		to.track.expectHandoff(ssrc, handoff)
+	}
+	if err := sender.ReplaceTrack(to.track); err != nil {
+		to.track.cancelHandoff(ssrc)
+		return err
+	}
+	// the peer cannot decode the new layer until its next key frame.
+	bs.RequestKeyFrame()
+	return nil
+}
+
+func (bs *basicStream) setMuted(sender *webrtc.RTPSender, muted bool) error {
+	kind, err := muteSender(sender, muted)
+	if err != nil {
+		return err
+	}
+	// the peer cannot decode the video it resumes until the next key frame.
+	if !muted && kind == webrtc.RTPCodecTypeVideo {
+		bs.RequestKeyFrame()
+	}
+	return nil
+}
+
+// mutableTrack is a track whose bindings can be muted.
+type mutableTrack interface {
+	webrtc.TrackLocal
+	setMuted(ssrc webrtc.SSRC, muted bool) error
+}
+
+// muteSender mutes or unmutes the binding of the given sender's track and returns the kind
+// of the track.
+func muteSender(sender *webrtc.RTPSender, muted bool) (webrtc.RTPCodecType, error) {
+	track, ok := sender.Track().(mutableTrack)
+	if !ok {
+		return 0, errors.New("sender's track cannot be muted")
+	}
+	encodings := sender.GetParameters().Encodings
+	if len(encodings) == 0 {
+		return 0, errors.New("sender has no encodings")
+	}
+	return track.Kind(), track.setMuted(encodings[0].SSRC, muted)
 }
 
 func (bs *basicStream) AudioTrackLocal() (webrtc.TrackLocal, bool) {
 	return bs.audioTrackLocal, bs.audioTrackLocal != nil
 }
 
+// processInputFrames is the capture stage of the video pipeline. It takes frames from the
+// source as soon as they are available so that the source never waits on the encoder, and
+// queues them for encoding at no more than the target frame rate. Frames that cannot be
+// encoded in time are dropped here, before encoding, so that latency does not build up.
 func (bs *basicStream) processInputFrames() {
-	frameLimiterDur := time.Second / time.Duration(bs.config.TargetFrameRate)
-	defer close(bs.outputVideoChan)
-	var dx, dy int
-	ticker := time.NewTicker(frameLimiterDur)
-	defer ticker.Stop()
+	targetFrameInterval := time.Second / time.Duration(bs.config.TargetFrameRate)
+	defer close(bs.encodeVideoChan)
+	var nextFrameDue time.Time
 	for {
+		var framePair MediaReleasePair[image.Image]
 		select {
+		case framePair = <-bs.inputImageChan:
 		case <-bs.shutdownCtx.Done():
 			return
-		default:
 		}
+		if framePair.Media == nil {
+			releaseFrame(framePair)
+			continue
+		}
+		bs.videoStats.framesReceived.Add(1)
+
+		// frames may arrive a little early due to jitter but the average rate still
+		// converges on the target frame rate.
+		frameInterval := targetFrameInterval
+		now := time.Now()
+		if now.Before(nextFrameDue.Add(-frameInterval / 4)) {
+			bs.videoStats.framesDroppedForFrameRate.Add(1)
+			releaseFrame(framePair)
+			continue
+		}
+		nextFrameDue = nextFrameDue.Add(frameInterval)
+		if nextFrameDue.Before(now.Add(-frameInterval)) {
+			nextFrameDue = now.Add(frameInterval)
+		}
+
+		// the newest frame replaces one still waiting on the encoder.
 		select {
-		case <-bs.shutdownCtx.Done():
-			return
-		case <-ticker.C:
+		case stale := <-bs.encodeVideoChan:
+			bs.videoStats.framesDroppedForBacklog.Add(1)
+			releaseFrame(stale.MediaReleasePair)
+		default:
 		}
-		var framePair MediaReleasePair[image.Image]
+		// this stage is the only sender so there is always room by now.
+		bs.encodeVideoChan <- capturedFrame{MediaReleasePair: framePair, capturedAt: now}
+	}
+}
+
+// releaseFrame releases the given frame if it has a release function.
+func releaseFrame(framePair MediaReleasePair[image.Image]) {
+	if framePair.Release != nil {
+		framePair.Release()
+	}
+}
+
+// encodeFrames is the encode stage of the video pipeline. It encodes queued frames once
+// per codec in use and passes them on to the send stage.
+func (bs *basicStream) encodeFrames() {
+	defer close(bs.outputVideoChan)
+	defer func() {
+		// release anything left behind by the capture stage.
+		for frame := range bs.encodeVideoChan {
+			releaseFrame(frame.MediaReleasePair)
+		}
+	}()
+	var dx, dy int
+	var lastForcedKeyFrame time.Time
+	// initFailed holds the layer and codec indexes whose encoder failed to be made so that it
+	// is not retried, and the failure logged, for every frame until the encoders are reset.
+	initFailed := map[[2]int]bool{}
+	for {
+		var frame capturedFrame
+		var ok bool
 		select {
-		case framePair = <-bs.inputImageChan:
+		case frame, ok = <-bs.encodeVideoChan:
+			if !ok {
+				return
+			}
 		case <-bs.shutdownCtx.Done():
 			return
 		}
-		if framePair.Media == nil {
-			continue
-		}
-		var initErr bool
 		func() {
-			if framePair.Release != nil {
-				defer framePair.Release()
-			}
+			defer releaseFrame(frame.MediaReleasePair)
 
-			bounds := framePair.Media.Bounds()
+			bounds := frame.Media.Bounds()
 			newDx, newDy := bounds.Dx(), bounds.Dy()
-			if bs.videoEncoder == nil || dx != newDx || dy != newDy {
+			if dx != newDx || dy != newDy {
 				dx, dy = newDx, newDy
 				bs.logger.Infow("detected new image bounds", "width", dx, "height", dy)
-
-				if err := bs.initVideoCodec(dx, dy); err != nil {
-					bs.logger.Error(err)
-					initErr = true
-					return
-				}
+				bs.resetVideoEncoders()
+				initFailed = map[[2]int]bool{}
 			}
 
-			// thread-safe because the size is static
-			encodedFrame, err := bs.videoEncoder.Encode(bs.shutdownCtx, framePair.Media)
-			if err != nil {
-				bs.logger.Error(err)
-				return
+			forceKeyFrame := bs.keyFrameRequested.Load() && time.Since(lastForcedKeyFrame) >= minKeyFrameRequestInterval
+			if forceKeyFrame {
+				bs.keyFrameRequested.Store(false)
+				lastForcedKeyFrame = time.Now()
 			}
-			if encodedFrame != nil {
-				select {
-				case <-bs.shutdownCtx.Done():
-					return
-				case bs.outputVideoChan <- encodedFrame:
+
+			// encode once for each layer and codec in use by a peer.
+			var encoded bool
+			for layerIdx, layer := range bs.videoLayers {
+				resolutionScale, frameRateScale := bs.congestionScale(layer)
+				if resolutionScale != layer.resolutionScale {
+					if layer.resolutionScale != 0 {
+						bs.logger.Infow("adapting resolution to congestion", "rid", layer.rid, "scale", resolutionScale)
+						for codecIdx := range bs.videoFactories {
+							bs.resetVideoEncoder(layer, codecIdx)
+							delete(initFailed, [2]int{layerIdx, codecIdx})
+						}
+					}
+					layer.resolutionScale = resolutionScale
+				}
+				// each layer adapts its frame rate on its own so frames are skipped per layer
+				// rather than dropped before encoding.
+				layer.framesSeen++
+				if layer.framesSeen%frameRateScale != 0 && !forceKeyFrame {
+					continue
+				}
+
+				var img image.Image
+				for codecIdx := range bs.videoFactories {
+					if !layer.track.codecInUse(codecIdx) || initFailed[[2]int{layerIdx, codecIdx}] {
+						bs.resetVideoEncoder(layer, codecIdx)
+						continue
+					}
+					// the frame is only scaled for layers a peer receives.
+					if img == nil {
+						img = layer.scaled(frame.Media, float64(resolutionScale))
+					}
+					encoder := layer.encoders[codecIdx]
+					if encoder == nil {
+						var err error
+						// a new encoder always starts with a key frame.
+						imgBounds := img.Bounds()
+						if encoder, err = bs.initVideoCodec(layer, codecIdx, imgBounds.Dx(), imgBounds.Dy()); err != nil {
+							// peers that negotiated another codec are still sent video.
+							bs.logger.Errorw("error making video encoder",
+								"mime_type", bs.videoFactories[codecIdx].MIMEType(), "rid", layer.rid, "error", err)
+							initFailed[[2]int{layerIdx, codecIdx}] = true
+							continue
+						}
+					} else if forceKeyFrame {
+						bs.forceKeyFrame(encoder)
+					}
+
+					// thread-safe because the size is static
+					var encodedFrame []byte
+					var temporalLayer int
+					var err error
+					if layered, ok := encoder.(codec.TemporalLayerEncoder); ok {
+						encodedFrame, temporalLayer, err = layered.EncodeLayered(bs.shutdownCtx, img)
+					} else {
+						encodedFrame, err = encoder.Encode(bs.shutdownCtx, img)
+					}
+					if err != nil {
+						bs.logger.Error(err)
+						continue
+					}
+					encoded = true
+					if encodedFrame != nil {
+						// waiting on the send stage holds up this stage which in turn makes the
+						// capture stage drop frames rather than encoded frames being dropped.
+						select {
+						case <-bs.shutdownCtx.Done():
+							return
+						case bs.outputVideoChan <- encodedData{
+							layerIdx:      layerIdx,
+							codecIdx:      codecIdx,
+							data:          encodedFrame,
+							temporalLayer: temporalLayer,
+							mediaTime:     frame.capturedAt,
+						}:
+						}
+					}
 				}
 			}
+			if encoded {
+				bs.videoStats.framesEncoded.Add(1)
+			}
 		}()
-		if initErr {
-			return
-		}
 	}
 }
 
 func (bs *basicStream) processInputAudioChunks() {
 	defer close(bs.outputAudioChan)
 	var samplingRate, channels int
+	// initFailed holds whether or not the encoder of each codec failed to be made so that it
+	// is not retried, and the failure logged, for every chunk until the audio changes.
+	initFailed := make([]bool, len(bs.audioFactories))
+	// sources may produce chunks of any size but encoders are handed exactly one frame at a time.
+	var rechunker audioRechunker
 	for {
 		select {
 		case <-bs.shutdownCtx.Done():
@@ -305,41 +1031,59 @@ func (bs *basicStream) processInputAudioChunks() {
 		if audioChunkPair.Media == nil {
 			continue
 		}
-		var initErr bool
-		func() {
-			if audioChunkPair.Release != nil {
-				defer audioChunkPair.Release()
+		// frames completed by this chunk are timestamped with when it was captured and the
+		// audio track keeps consecutive frames evenly spaced from there.
+		capturedAt := time.Now()
+
+		info := audioChunkPair.Media.ChunkInfo()
+		newSamplingRate, newChannels := info.SamplingRate, info.Channels
+		if samplingRate != newSamplingRate || channels != newChannels {
+			samplingRate, channels = newSamplingRate, newChannels
+			bs.logger.Infow("detected new audio info", "sampling_rate", samplingRate, "channels", channels)
+
+			bs.audioTrackLocal.setAudioLatency(bs.audioLatency)
+			rechunker.frameDuration = bs.audioLatency
+			for codecIdx := range bs.audioEncoders {
+				bs.resetAudioEncoder(codecIdx)
+				initFailed[codecIdx] = false
 			}
+		}
+		rechunker.bufferChunk(audioChunkPair.Media)
+		if audioChunkPair.Release != nil {
+			audioChunkPair.Release()
+		}
 
-			info := audioChunkPair.Media.ChunkInfo()
-			newSamplingRate, newChannels := info.SamplingRate, info.Channels
-			if samplingRate != newSamplingRate || channels != newChannels {
-				samplingRate, channels = newSamplingRate, newChannels
-				bs.logger.Infow("detected new audio info", "sampling_rate", samplingRate, "channels", channels)
+		for chunk, ok := rechunker.next(); ok; chunk, ok = rechunker.next() {
+			// encode once for each codec in use by a peer.
+			for codecIdx := range bs.audioFactories {
+				if !bs.audioTrackLocal.codecInUse(codecIdx) || initFailed[codecIdx] {
+					bs.resetAudioEncoder(codecIdx)
+					continue
+				}
+				encoder := bs.audioEncoders[codecIdx]
+				if encoder == nil {
+					var err error
+					if encoder, err = bs.initAudioCodec(codecIdx, samplingRate, channels); err != nil {
+						// peers that negotiated another codec are still sent audio.
+						bs.logger.Errorw("error making audio encoder", "mime_type", bs.audioFactories[codecIdx].MIMEType(), "error", err)
+						initFailed[codecIdx] = true
+						continue
+					}
+				}
 
-				bs.audioTrackLocal.setAudioLatency(bs.audioLatency)
-				if err := bs.initAudioCodec(samplingRate, channels); err != nil {
+				encodedChunk, ready, err := encoder.Encode(bs.shutdownCtx, chunk)
+				if err != nil {
 					bs.logger.Error(err)
-					initErr = true
-					return
+					continue
 				}
-			}
-
-			encodedChunk, ready, err := bs.audioEncoder.Encode(bs.shutdownCtx, audioChunkPair.Media)
-			if err != nil {
-				bs.logger.Error(err)
-				return
-			}
-			if ready && encodedChunk != nil {
-				select {
-				case <-bs.shutdownCtx.Done():
-					return
-				case bs.outputAudioChan <- encodedChunk:
+				if ready && encodedChunk != nil {
+					select {
+					case <-bs.shutdownCtx.Done():
+						return
+					case bs.outputAudioChan <- encodedData{codecIdx: codecIdx, data: encodedChunk, mediaTime: capturedAt}:
+					}
 				}
 			}
-		}()
-		if initErr {
-			return
 		}
 	}
 }
@@ -353,7 +1097,8 @@ func (bs *basicStream) processOutputFrames() {
 		default:
 		}
 		now := time.Now()
-		if err := bs.videoTrackLocal.WriteData(outputFrame); err != nil {
+		track := bs.videoLayers[outputFrame.layerIdx].track
+		if err := track.WriteData(outputFrame.codecIdx, outputFrame.data, outputFrame.temporalLayer, outputFrame.mediaTime); err != nil {
 			bs.logger.Errorw("error writing frame", "error", err)
 		}
 		framesSent++
@@ -372,7 +1117,8 @@ func (bs *basicStream) processOutputAudioChunks() {
 		default:
 		}
 		now := time.Now()
-		if err := bs.audioTrackLocal.WriteData(outputChunk); err != nil {
+		err := bs.audioTrackLocal.WriteData(outputChunk.codecIdx, outputChunk.data, outputChunk.temporalLayer, outputChunk.mediaTime)
+		if err != nil {
 			bs.logger.Errorw("error writing audio chunk", "error", err)
 		}
 		chunksSent++
@@ -382,17 +1128,59 @@ func (bs *basicStream) processOutputAudioChunks() {
 	}
 }
 
-func (bs *basicStream) initVideoCodec(width, height int) error {
-	var err error
-	bs.videoEncoder, err = bs.config.VideoEncoderFactory.New(width, height, bs.config.TargetFrameRate, bs.logger)
-	return err
+func (bs *basicStream) initVideoCodec(layer *videoLayer, codecIdx, width, height int) (codec.VideoEncoder, error) {
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	opts := bs.config.VideoEncoderOptions
+	opts.TargetBitrate = bs.layerBitrate(layer)
+	encoder, err := bs.videoFactories[codecIdx].New(
+		width, height, bs.config.TargetFrameRate, opts, bs.logger)
+	if err != nil {
+		return nil, err
+	}
+	layer.encoders[codecIdx] = encoder
+	return encoder, nil
 }
 
-func (bs *basicStream) initAudioCodec(sampleRate, channelCount int) error {
-	var err error
-	if bs.audioEncoder != nil {
-		bs.audioEncoder.Close()
+// resetVideoEncoder closes the video encoder of the given layer and codec so that a new one
+// is made when it is next needed.
+func (bs *basicStream) resetVideoEncoder(layer *videoLayer, codecIdx int) {
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	if layer.encoders[codecIdx] != nil {
+		layer.encoders[codecIdx].Close()
+		layer.encoders[codecIdx] = nil
+	}
+}
+
+// resetVideoEncoders closes all video encoders.
+func (bs *basicStream) resetVideoEncoders() {
+	for _, layer := range bs.videoLayers {
+		for codecIdx := range layer.encoders {
+			bs.resetVideoEncoder(layer, codecIdx)
+		}
+	}
+}
+
+func (bs *basicStream) initAudioCodec(codecIdx, sampleRate, channelCount int) (codec.AudioEncoder, error) {
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	encoder, err := bs.audioFactories[codecIdx].New(
+		sampleRate, channelCount, bs.audioLatency, bs.config.AudioEncoderOptions, bs.logger)
+	if err != nil {
+		return nil, err
+	}
+	bs.audioEncoders[codecIdx] = encoder
+	return encoder, nil
+}
+
+// resetAudioEncoder closes the audio encoder of the given codec so that a new one is made
+// when it is next needed.
+func (bs *basicStream) resetAudioEncoder(codecIdx int) {
+	bs.encoderMu.Lock()
+	defer bs.encoderMu.Unlock()
+	if bs.audioEncoders[codecIdx] != nil {
+		bs.audioEncoders[codecIdx].Close()
+		bs.audioEncoders[codecIdx] = nil
 	}
-	bs.audioEncoder, err = bs.config.AudioEncoderFactory.New(sampleRate, channelCount, bs.audioLatency, bs.logger)
-	return err
 }
@@ -12,8 +12,59 @@ type StreamConfig struct {
 	VideoEncoderFactory codec.VideoEncoderFactory
 	AudioEncoderFactory codec.AudioEncoderFactory
 
+	// Registry optionally offers additional codecs. Each peer is sent the first codec it
+	// supports, starting with VideoEncoderFactory and AudioEncoderFactory followed by the
+	// registry's factories in the order they were registered.
+	Registry *codec.Registry
+
+	// VideoEncoderOptions and AudioEncoderOptions are passed to their respective
+	// factories whenever a new encoder is made.
+	VideoEncoderOptions codec.VideoEncoderOptions
+	AudioEncoderOptions codec.AudioEncoderOptions
+
 	// TargetFrameRate will hint to the stream to try to maintain this frame rate.
 	TargetFrameRate int
 
+	// NACKHistorySize is how many of the most recently sent video packets are kept for each
+	// viewer in order to retransmit the ones it reports lost. Viewers that negotiated RTX are
+	// sent retransmissions as RTX and others the packets as they were first sent. The NACK
+	// responder of peer connections that register pion's default interceptors, as
+	// webrtc.NewPeerConnection and those of a StreamServer do, is bypassed for the video so
+	// that packets are not retransmitted twice. pion cannot announce the RTX SSRC in the
+	// session description, so viewers must accept RTX on an SSRC they were not told of. Zero
+	// or a negative size leaves retransmission to the peer connection.
+	NACKHistorySize int
+
+	// FECOverhead is the fraction of video packets, between 0 and 1, additionally sent as
+	// forward error correction so that viewers can recover lost packets without waiting on a
+	// retransmission. Only viewers whose peer connections negotiated it receive forward error
+	// correction, see RegisterFECCodecs; all others are sent the video alone. The peer
+	// connections of a StreamServer do not negotiate it yet since go.viam.com/utils/rpc makes
+	// them with pion's default codecs, which lack RED and ULPFEC, and offers no way of
+	// registering more. Zero disables it.
+	FECOverhead float64
+
+	// Simulcast, when set, encodes the video once for each of the given layers instead of
+	// once at the source's resolution. Each layer is a separate video track tagged with the
+	// layer's RID and every peer is sent one of them, the first layer by default.
+	Simulcast []SimulcastLayer
+
+	// CongestionControl, when set, adapts the video to the bandwidth available to viewers.
+	CongestionControl *CongestionControlConfig
+
 	Logger golog.Logger
 }
+
+// A SimulcastLayer is one encoding of a stream's video.
+type SimulcastLayer struct {
+	// RID identifies the layer to peers. It must be unique within the stream.
+	RID string
+
+	// ScaleResolutionDownBy divides the width and height of the source. Zero or one keeps
+	// the source's resolution.
+	ScaleResolutionDownBy float64
+
+	// TargetBitrate is the layer's bitrate in bits per second. Zero uses the target bitrate
+	// of VideoEncoderOptions, which Stream.SetVideoBitrate then also changes.
+	TargetBitrate int
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pion/webrtc/v3"
 	"go.uber.org/multierr"
 	"go.viam.com/utils"
 	"go.viam.com/utils/rpc"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	streampb "github.com/edaniels/gostream/proto/stream/v1"
 )
@@ -37,6 +41,14 @@ type StreamServer interface {
 	// AddStream adds the given stream for new connections to see.
 	AddStream(stream Stream) error
 
+	// PeerStats returns statistics about the connection to every peer that was sent a stream
+	// and about the streams sent to it.
+	PeerStats() []PeerStats
+
+	// StreamPeerStats is like PeerStats but only includes the peers sent the stream with the
+	// given name and only that stream's statistics.
+	StreamPeerStats(name string) ([]PeerStats, error)
+
 	// Close closes the server.
 	Close() error
 }
@@ -46,7 +58,7 @@ type StreamServer interface {
 func NewStreamServer(streams ...Stream) (StreamServer, error) {
 	ss := &streamServer{
 		nameToStream:      map[string]Stream{},
-		activePeerStreams: map[*webrtc.PeerConnection]map[string]*peerState{},
+		activePeerStreams: map[*webrtc.PeerConnection]*activePeer{},
 	}
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
@@ -83,16 +95,45 @@ func (ss *streamState) Stop() {
 	}
 }
 
+// activePeer is a peer that was sent at least one stream.
+type activePeer struct {
+	id      string
+	streams map[string]*peerState
+	// stopSampling stops sampling the peer's stats.
+	stopSampling func()
+
+	// statsMu guards the peer's bitrates and what they are computed from.
+	statsMu            sync.Mutex
+	lastSampleAt       time.Time
+	lastBytesSent      uint64
+	lastTrackBytesSent map[webrtc.SSRC]uint64
+	bitrate            float64
+	trackBitrates      map[webrtc.SSRC]float64
+}
+
+// peerStreams returns the streams sent to the peer. It must be called with the server's
+// mutex held.
+func (p *activePeer) peerStreams() []peerStream {
+	streams := make([]peerStream, 0, len(p.streams))
+	for _, ps := range p.streams {
+		streams = append(streams, peerStream{ps.stream.stream, ps.senders})
+	}
+	return streams
+}
+
 type peerState struct {
 	stream  *streamState
 	senders []*webrtc.RTPSender
+	// videoSender and audioSender send the stream's video and audio to the peer, if it has any.
+	videoSender *webrtc.RTPSender
+	audioSender *webrtc.RTPSender
 }
 
 type streamServer struct {
 	mu                      sync.RWMutex
 	streams                 []*streamState
 	nameToStream            map[string]Stream
-	activePeerStreams       map[*webrtc.PeerConnection]map[string]*peerState
+	activePeerStreams       map[*webrtc.PeerConnection]*activePeer
 	activeBackgroundWorkers sync.WaitGroup
 }
 
@@ -133,13 +174,90 @@ func (ss *streamServer) addStream(stream Stream) error {
 	return nil
 }
 
+func (ss *streamServer) PeerStats() []PeerStats {
+	stats, _ := ss.peerStats("", nil)
+	return stats
+}
+
+func (ss *streamServer) StreamPeerStats(name string) ([]PeerStats, error) {
+	return ss.peerStats(name, nil)
+}
+
+// peerStats gathers the stats of all peers, or only of the peer of the given connection if it
+// is not nil, limited to the stream with the given name if it is not empty.
+func (ss *streamServer) peerStats(name string, only *webrtc.PeerConnection) ([]PeerStats, error) {
+	type peerStreams struct {
+		pc      *webrtc.PeerConnection
+		peer    *activePeer
+		streams []peerStream
+	}
+	ss.mu.RLock()
+	if _, ok := ss.nameToStream[name]; name != "" && !ok {
+		ss.mu.RUnlock()
+		return nil, fmt.Errorf("no stream for %q", name)
+	}
+	peers := make([]peerStreams, 0, len(ss.activePeerStreams))
+	for pc, peer := range ss.activePeerStreams {
+		if only != nil && pc != only {
+			continue
+		}
+		if _, ok := peer.streams[name]; name != "" && !ok {
+			continue
+		}
+		peers = append(peers, peerStreams{pc, peer, peer.peerStreams()})
+	}
+	ss.mu.RUnlock()
+
+	// gathering stats waits on each connection so it is done without holding the lock.
+	stats := make([]PeerStats, 0, len(peers))
+	for _, peer := range peers {
+		peerStats := peer.peer.gatherStats(peer.pc, peer.streams)
+		if name != "" {
+			var streamStats []PeerStreamStats
+			for _, s := range peerStats.Streams {
+				if s.Name == name {
+					streamStats = append(streamStats, s)
+				}
+			}
+			peerStats.Streams = streamStats
+		}
+		stats = append(stats, peerStats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ID < stats[j].ID
+	})
+	return stats, nil
+}
+
+// sampleStats samples the stats of the given peer every statsSampleInterval until the given
+// context is done.
+func (ss *streamServer) sampleStats(ctx context.Context, pc *webrtc.PeerConnection, peer *activePeer) {
+	ticker := time.NewTicker(statsSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ss.mu.RLock()
+		streams := peer.peerStreams()
+		ss.mu.RUnlock()
+		peer.sampleStats(pc, streams)
+	}
+}
+
 func (ss *streamServer) Close() error {
 	ss.mu.RLock()
-	defer ss.mu.RUnlock()
 	for _, stream := range ss.streams {
 		stream.stream.Stop()
 		stream.activePeers = 0
 	}
+	for _, peer := range ss.activePeerStreams {
+		peer.stopSampling()
+	}
+	ss.mu.RUnlock()
+	// background workers may need the lock.
 	ss.activeBackgroundWorkers.Wait()
 	return nil
 }
@@ -180,12 +298,23 @@ func (srs *streamRPCServer) AddStream(ctx context.Context, req *streampb.AddStre
 	srs.ss.mu.Lock()
 	defer srs.ss.mu.Unlock()
 
-	if _, ok := srs.ss.activePeerStreams[pc][req.Name]; ok {
-		return nil, errors.New("stream already active")
-	}
-	pcStreams, ok := srs.ss.activePeerStreams[pc]
-	if !ok {
-		pcStreams = map[string]*peerState{}
+	peer, ok := srs.ss.activePeerStreams[pc]
+	if ok {
+		if _, ok := peer.streams[req.Name]; ok {
+			return nil, errors.New("stream already active")
+		}
+	} else {
+		samplingCtx, stopSampling := context.WithCancel(context.Background())
+		peer = &activePeer{
+			id:           uuid.NewString(),
+			streams:      map[string]*peerState{},
+			stopSampling: stopSampling,
+			lastSampleAt: time.Now(),
+		}
+		srs.ss.activeBackgroundWorkers.Add(1)
+		utils.ManagedGo(func() {
+			srs.ss.sampleStats(samplingCtx, pc, peer)
+		}, srs.ss.activeBackgroundWorkers.Done)
 		pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
 			switch state {
 			case webrtc.PeerConnectionStateDisconnected,
@@ -198,8 +327,11 @@ func (srs *streamRPCServer) AddStream(ctx context.Context, req *streampb.AddStre
 					srs.ss.mu.Lock()
 					defer srs.ss.mu.Unlock()
 					defer delete(srs.ss.activePeerStreams, pc)
-					for _, ps := range srs.ss.activePeerStreams[pc] {
-						ps.stream.Stop()
+					if peer, ok := srs.ss.activePeerStreams[pc]; ok {
+						peer.stopSampling()
+						for _, ps := range peer.streams {
+							ps.stream.Stop()
+						}
 					}
 				})
 			case webrtc.PeerConnectionStateConnected,
@@ -210,13 +342,13 @@ func (srs *streamRPCServer) AddStream(ctx context.Context, req *streampb.AddStre
 				return
 			}
 		})
-		srs.ss.activePeerStreams[pc] = pcStreams
+		srs.ss.activePeerStreams[pc] = peer
 	}
 
-	ps, ok := pcStreams[req.Name]
+	ps, ok := peer.streams[req.Name]
 	if !ok {
 		ps = &peerState{stream: streamToAdd}
-		pcStreams[req.Name] = ps
+		peer.streams[req.Name] = ps
 	}
 
 	var successful bool
@@ -228,24 +360,38 @@ func (srs *streamRPCServer) AddStream(ctx context.Context, req *streampb.AddStre
 		}
 	}()
 
-	addTrack := func(track webrtc.TrackLocal) error {
+	addTrack := func(track webrtc.TrackLocal) (*webrtc.RTPSender, error) {
 		sender, err := pc.AddTrack(track)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		ps.senders = append(ps.senders, sender)
-		return nil
+		utils.PanicCapturingGo(func() {
+			readSenderRTCP(sender, streamToAdd.stream)
+		})
+		return sender, nil
 	}
 
-	if trackLocal, haveTrackLocal := streamToAdd.stream.VideoTrackLocal(); haveTrackLocal {
-		if err := addTrack(trackLocal); err != nil {
+	videoTrackLocal, haveVideoTrackLocal := streamToAdd.stream.VideoTrackLocal()
+	if req.Rid != "" {
+		videoTrackLocal, haveVideoTrackLocal = streamToAdd.stream.VideoTrackLocalForRID(req.Rid)
+		if !haveVideoTrackLocal {
+			return nil, fmt.Errorf("no video layer %q for stream %q", req.Rid, req.Name)
+		}
+	}
+	if haveVideoTrackLocal {
+		sender, err := addTrack(videoTrackLocal)
+		if err != nil {
 			return nil, err
 		}
+		ps.videoSender = sender
 	}
 	if trackLocal, haveTrackLocal := streamToAdd.stream.AudioTrackLocal(); haveTrackLocal {
-		if err := addTrack(trackLocal); err != nil {
+		sender, err := addTrack(trackLocal)
+		if err != nil {
 			return nil, err
 		}
+		ps.audioSender = sender
 	}
 	streamToAdd.Start()
 
@@ -253,6 +399,18 @@ func (srs *streamRPCServer) AddStream(ctx context.Context, req *streampb.AddStre
 	return &streampb.AddStreamResponse{}, nil
 }
 
+// readSenderRTCP passes the RTCP sent back by a viewer for the given sender to the
+// stream until the sender is stopped.
+func readSenderRTCP(sender *webrtc.RTPSender, stream Stream) {
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		stream.handleRTCP(packets)
+	}
+}
+
 func (srs *streamRPCServer) RemoveStream(ctx context.Context, req *streampb.RemoveStreamRequest) (*streampb.RemoveStreamResponse, error) {
 	pc, ok := rpc.ContextPeerConnection(ctx)
 	if !ok {
@@ -274,15 +432,20 @@ func (srs *streamRPCServer) RemoveStream(ctx context.Context, req *streampb.Remo
 	srs.ss.mu.Lock()
 	defer srs.ss.mu.Unlock()
 
-	if _, ok := srs.ss.activePeerStreams[pc][req.Name]; !ok {
+	peer, ok := srs.ss.activePeerStreams[pc]
+	if !ok {
+		return nil, errors.New("stream already inactive")
+	}
+	ps, ok := peer.streams[req.Name]
+	if !ok {
 		return nil, errors.New("stream already inactive")
 	}
 	defer func() {
-		delete(srs.ss.activePeerStreams[pc], req.Name)
+		delete(peer.streams, req.Name)
 	}()
 
 	var errs error
-	for _, sender := range srs.ss.activePeerStreams[pc][req.Name].senders {
+	for _, sender := range ps.senders {
 		errs = multierr.Combine(errs, pc.RemoveTrack(sender))
 	}
 	if errs != nil {
@@ -296,3 +459,129 @@ func (srs *streamRPCServer) RemoveStream(ctx context.Context, req *streampb.Remo
 
 	return &streampb.RemoveStreamResponse{}, nil
 }
+
+func (srs *streamRPCServer) SetStreamLayer(
+	ctx context.Context,
+	req *streampb.SetStreamLayerRequest,
+) (*streampb.SetStreamLayerResponse, error) {
+	pc, ok := rpc.ContextPeerConnection(ctx)
+	if !ok {
+		return nil, errors.New("can only set a stream layer over a WebRTC based connection")
+	}
+
+	srs.ss.mu.Lock()
+	defer srs.ss.mu.Unlock()
+
+	peer, ok := srs.ss.activePeerStreams[pc]
+	if !ok {
+		return nil, errors.New("stream not active")
+	}
+	ps, ok := peer.streams[req.Name]
+	if !ok {
+		return nil, errors.New("stream not active")
+	}
+	if ps.videoSender == nil {
+		return nil, fmt.Errorf("no video in stream %q", req.Name)
+	}
+	if err := ps.stream.stream.switchVideoLayer(ps.videoSender, req.Rid); err != nil {
+		return nil, err
+	}
+	return &streampb.SetStreamLayerResponse{}, nil
+}
+
+func (srs *streamRPCServer) MuteStream(ctx context.Context, req *streampb.MuteStreamRequest) (*streampb.MuteStreamResponse, error) {
+	pc, ok := rpc.ContextPeerConnection(ctx)
+	if !ok {
+		return nil, errors.New("can only mute a stream over a WebRTC based connection")
+	}
+
+	srs.ss.mu.Lock()
+	defer srs.ss.mu.Unlock()
+
+	peer, ok := srs.ss.activePeerStreams[pc]
+	if !ok {
+		return nil, errors.New("stream not active")
+	}
+	ps, ok := peer.streams[req.Name]
+	if !ok {
+		return nil, errors.New("stream not active")
+	}
+	if req.Audio != nil {
+		if ps.audioSender == nil {
+			return nil, fmt.Errorf("no audio in stream %q", req.Name)
+		}
+		if err := ps.stream.stream.setMuted(ps.audioSender, *req.Audio); err != nil {
+			return nil, err
+		}
+	}
+	if req.Video != nil {
+		if ps.videoSender == nil {
+			return nil, fmt.Errorf("no video in stream %q", req.Name)
+		}
+		if err := ps.stream.stream.setMuted(ps.videoSender, *req.Video); err != nil {
+			return nil, err
+		}
+	}
+	return &streampb.MuteStreamResponse{}, nil
+}
+
+func (srs *streamRPCServer) GetStats(ctx context.Context, req *streampb.GetStatsRequest) (*streampb.GetStatsResponse, error) {
+	// other peers' addresses are not given out.
+	pc, ok := rpc.ContextPeerConnection(ctx)
+	if !ok {
+		return nil, errors.New("can only get stats over a WebRTC based connection")
+	}
+	stats, err := srs.ss.peerStats(req.Name, pc)
+	if err != nil {
+		return nil, err
+	}
+	peers := make([]*streampb.PeerStats, 0, len(stats))
+	for _, peerStats := range stats {
+		peers = append(peers, peerStatsToProto(peerStats))
+	}
+	return &streampb.GetStatsResponse{Peers: peers}, nil
+}
+
+func peerStatsToProto(stats PeerStats) *streampb.PeerStats {
+	pbStats := &streampb.PeerStats{
+		Id:            stats.ID,
+		RoundTripTime: durationpb.New(stats.RoundTripTime),
+		BytesSent:     stats.BytesSent,
+		BytesReceived: stats.BytesReceived,
+		Bitrate:       stats.Bitrate,
+	}
+	if pair := stats.SelectedCandidatePair; pair != nil {
+		pbStats.SelectedCandidatePair = &streampb.CandidatePair{
+			Local:  candidateStatsToProto(pair.Local),
+			Remote: candidateStatsToProto(pair.Remote),
+		}
+	}
+	for _, streamStats := range stats.Streams {
+		pbStreamStats := &streampb.PeerStreamStats{Name: streamStats.Name}
+		for _, trackStats := range streamStats.Tracks {
+			pbStreamStats.Tracks = append(pbStreamStats.Tracks, &streampb.TrackStats{
+				Kind:         trackStats.Kind,
+				Rid:          trackStats.RID,
+				Ssrc:         uint32(trackStats.SSRC),
+				MimeType:     trackStats.MimeType,
+				PacketsSent:  trackStats.PacketsSent,
+				BytesSent:    trackStats.BytesSent,
+				Bitrate:      trackStats.Bitrate,
+				FractionLost: trackStats.FractionLost,
+				TotalLost:    trackStats.TotalLost,
+				Jitter:       durationpb.New(trackStats.Jitter),
+			})
+		}
+		pbStats.Streams = append(pbStats.Streams, pbStreamStats)
+	}
+	return pbStats
+}
+
+func candidateStatsToProto(stats CandidateStats) *streampb.Candidate {
+	return &streampb.Candidate{
+		Address:  stats.Address,
+		Port:     uint32(stats.Port),
+		Protocol: stats.Protocol,
+		Type:     stats.Type,
+	}
+}
@@ -0,0 +1,39 @@
+package gostream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edaniels/golog"
+	"go.viam.com/test"
+)
+
+func TestStreamServerAddStream(t *testing.T) {
+	server, err := NewStreamServer()
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, server.Close(), test.ShouldBeNil)
+	}()
+
+	// streams answer NACKs in place of the peer connection's NACK responder and only send FEC
+	// to viewers that negotiated it.
+	for _, config := range []StreamConfig{
+		{Name: "plain"},
+		{Name: "history", NACKHistorySize: DefaultNACKHistorySize},
+		{Name: "fec", FECOverhead: 0.2},
+	} {
+		config.VideoEncoderFactory = &slowVideoEncoderFactory{}
+		config.Logger = golog.NewTestLogger(t)
+		_, err = server.NewStream(config)
+		test.That(t, err, test.ShouldBeNil)
+	}
+
+	stream, err := NewStream(StreamConfig{
+		Name:                "fec",
+		VideoEncoderFactory: &slowVideoEncoderFactory{},
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+	var alreadyRegistered *StreamAlreadyRegisteredError
+	test.That(t, errors.As(server.AddStream(stream), &alreadyRegistered), test.ShouldBeTrue)
+}
@@ -0,0 +1,360 @@
+package gostream
+
+import (
+	"context"
+	"image"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pkg/errors"
+	"go.viam.com/test"
+
+	"github.com/edaniels/gostream/codec"
+)
+
+type slowVideoEncoder struct {
+	delay time.Duration
+}
+
+func (e *slowVideoEncoder) Encode(ctx context.Context, img image.Image) ([]byte, error) {
+	time.Sleep(e.delay)
+	return []byte{1}, nil
+}
+
+func (e *slowVideoEncoder) Close() {}
+
+type slowVideoEncoderFactory struct {
+	delay time.Duration
+}
+
+func (f *slowVideoEncoderFactory) New(
+	width, height, keyFrameInterval int,
+	opts codec.VideoEncoderOptions,
+	logger golog.Logger,
+) (codec.VideoEncoder, error) {
+	return &slowVideoEncoder{delay: f.delay}, nil
+}
+
+func (f *slowVideoEncoderFactory) MIMEType() string {
+	return "video/vp8"
+}
+
+func TestVideoPipelineDropsBeforeEncoding(t *testing.T) {
+	stream, err := NewStream(StreamConfig{
+		VideoEncoderFactory: &slowVideoEncoderFactory{delay: 20 * time.Millisecond},
+		TargetFrameRate:     1000,
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+	bs := stream.(*basicStream)
+	bs.videoLayers[0].track.rtpTrack.bindings = []trackBinding{{ssrc: 1, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1}}
+	stream.Start()
+
+	input, err := stream.InputVideoFrames(prop.Video{})
+	test.That(t, err, test.ShouldBeNil)
+
+	const numFrames = 50
+	var released atomic.Int32
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	start := time.Now()
+	for i := 0; i < numFrames; i++ {
+		input <- MediaReleasePair[image.Image]{img, func() { released.Add(1) }}
+		time.Sleep(2 * time.Millisecond)
+	}
+	// the source must not wait on an encoder that takes 20ms per frame.
+	test.That(t, time.Since(start), test.ShouldBeLessThan, numFrames*20*time.Millisecond/2)
+	stream.Stop()
+
+	stats := stream.VideoFrameStats()
+	test.That(t, stats.FramesReceived, test.ShouldEqual, numFrames)
+	test.That(t, stats.FramesDroppedForBacklog, test.ShouldBeGreaterThan, 0)
+	test.That(t, stats.FramesEncoded, test.ShouldBeLessThan, numFrames)
+	test.That(t, stats.FramesEncoded+stats.FramesDroppedForBacklog+stats.FramesDroppedForFrameRate,
+		test.ShouldBeLessThanOrEqualTo, numFrames)
+	test.That(t, released.Load(), test.ShouldEqual, numFrames)
+}
+
+type recordingVideoEncoderFactory struct {
+	mu      sync.Mutex
+	sizes   []image.Point
+	bitrate map[image.Point]int
+}
+
+func (f *recordingVideoEncoderFactory) New(
+	width, height, keyFrameInterval int,
+	opts codec.VideoEncoderOptions,
+	logger golog.Logger,
+) (codec.VideoEncoder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	size := image.Pt(width, height)
+	f.sizes = append(f.sizes, size)
+	f.bitrate[size] = opts.TargetBitrate
+	return &slowVideoEncoder{}, nil
+}
+
+func (f *recordingVideoEncoderFactory) MIMEType() string {
+	return "video/vp8"
+}
+
+func TestSimulcastLayers(t *testing.T) {
+	factory := &recordingVideoEncoderFactory{bitrate: map[image.Point]int{}}
+	for _, layers := range [][]SimulcastLayer{
+		{{RID: "f"}, {}},
+		{{RID: "f"}, {RID: "f"}},
+		{{RID: "f", ScaleResolutionDownBy: 0.5}},
+		{{RID: "f", TargetBitrate: -1}},
+	} {
+		_, err := NewStream(StreamConfig{VideoEncoderFactory: factory, Simulcast: layers})
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+
+	stream, err := NewStream(StreamConfig{
+		VideoEncoderFactory: factory,
+		VideoEncoderOptions: codec.VideoEncoderOptions{TargetBitrate: 1000},
+		Simulcast: []SimulcastLayer{
+			{RID: "f"},
+			{RID: "h", ScaleResolutionDownBy: 2},
+			{RID: "q", ScaleResolutionDownBy: 4, TargetBitrate: 100},
+		},
+		Logger: golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+	for _, rid := range []string{"f", "h", "q"} {
+		track, ok := stream.VideoTrackLocalForRID(rid)
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, track.RID(), test.ShouldEqual, rid)
+	}
+	_, ok := stream.VideoTrackLocalForRID("x")
+	test.That(t, ok, test.ShouldBeFalse)
+	track, _ := stream.VideoTrackLocal()
+	test.That(t, track.RID(), test.ShouldEqual, "f")
+
+	// only the layers a peer receives are encoded.
+	bs := stream.(*basicStream)
+	for _, layerIdx := range []int{0, 2} {
+		bs.videoLayers[layerIdx].track.rtpTrack.bindings = []trackBinding{{ssrc: 1, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1}}
+	}
+	stream.Start()
+	input, err := stream.InputVideoFrames(prop.Video{})
+	test.That(t, err, test.ShouldBeNil)
+	input <- MediaReleasePair[image.Image]{image.NewRGBA(image.Rect(0, 0, 64, 48)), nil}
+	for deadline := time.Now().Add(time.Second); stream.VideoFrameStats().FramesEncoded == 0; {
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond)
+	}
+	stream.Stop()
+
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	test.That(t, factory.sizes, test.ShouldResemble, []image.Point{{64, 48}, {16, 12}})
+	test.That(t, factory.bitrate[image.Pt(64, 48)], test.ShouldEqual, 1000)
+	test.That(t, factory.bitrate[image.Pt(16, 12)], test.ShouldEqual, 100)
+}
+
+// fixedVideoEncoderFactory makes encoders of the given MIME type that encode every frame
+// to the same data, or that fail to be made if no data is set.
+type fixedVideoEncoderFactory struct {
+	mimeType string
+	data     []byte
+}
+
+func (f *fixedVideoEncoderFactory) New(
+	width, height, keyFrameInterval int,
+	opts codec.VideoEncoderOptions,
+	logger golog.Logger,
+) (codec.VideoEncoder, error) {
+	if f.data == nil {
+		return nil, errors.Errorf("cannot make %s encoder", f.mimeType)
+	}
+	return &fixedVideoEncoder{data: f.data}, nil
+}
+
+func (f *fixedVideoEncoderFactory) MIMEType() string {
+	return f.mimeType
+}
+
+type fixedVideoEncoder struct {
+	data []byte
+}
+
+func (e *fixedVideoEncoder) Encode(ctx context.Context, img image.Image) ([]byte, error) {
+	return e.data, nil
+}
+
+func (e *fixedVideoEncoder) Close() {}
+
+// lockedTrackLocalWriter is a fakeTrackLocalWriter that can be read while a stream writes to it.
+type lockedTrackLocalWriter struct {
+	mu     sync.Mutex
+	writer fakeTrackLocalWriter
+}
+
+func (w *lockedTrackLocalWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer.WriteRTP(header, payload)
+}
+
+func (w *lockedTrackLocalWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *lockedTrackLocalWriter) written() ([]rtp.Header, [][]byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]rtp.Header(nil), w.writer.headers...), append([][]byte(nil), w.writer.payloads...)
+}
+
+func TestCodecPerPeer(t *testing.T) {
+	registry := codec.NewRegistry()
+	test.That(t, registry.RegisterVideoEncoderFactory(&fixedVideoEncoderFactory{
+		mimeType: webrtc.MimeTypeH264,
+		data:     []byte{0, 0, 0, 1, 0x41, 2},
+	}), test.ShouldBeNil)
+	test.That(t, registry.RegisterVideoEncoderFactory(&fixedVideoEncoderFactory{mimeType: webrtc.MimeTypeAV1}), test.ShouldBeNil)
+	stream, err := NewStream(StreamConfig{
+		VideoEncoderFactory: &fixedVideoEncoderFactory{mimeType: webrtc.MimeTypeVP8, data: []byte{1}},
+		Registry:            registry,
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	track, ok := stream.VideoTrackLocal()
+	test.That(t, ok, test.ShouldBeTrue)
+	var vp8Writer, h264Writer, av1Writer lockedTrackLocalWriter
+	for _, peer := range []struct {
+		ctx      *fakeTrackLocalContext
+		mimeType string
+	}{
+		{&fakeTrackLocalContext{id: "vp8", ssrc: 1, writeStream: &vp8Writer}, webrtc.MimeTypeVP8},
+		{&fakeTrackLocalContext{id: "h264", ssrc: 2, writeStream: &h264Writer, codecs: []webrtc.RTPCodecParameters{{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000},
+			PayloadType:        102,
+		}}}, webrtc.MimeTypeH264},
+		{&fakeTrackLocalContext{id: "av1", ssrc: 3, writeStream: &av1Writer, codecs: []webrtc.RTPCodecParameters{{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeAV1, ClockRate: 90000},
+			PayloadType:        45,
+		}}}, webrtc.MimeTypeAV1},
+	} {
+		bound, err := track.Bind(peer.ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, bound.MimeType, test.ShouldEqual, peer.mimeType)
+	}
+
+	stream.Start()
+	defer stream.Stop()
+	input, err := stream.InputVideoFrames(prop.Video{})
+	test.That(t, err, test.ShouldBeNil)
+
+	// the AV1 encoder failing to be made must not stop the other peers from being sent video.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for deadline := time.Now().Add(time.Second); ; {
+		vp8Headers, _ := vp8Writer.written()
+		h264Headers, _ := h264Writer.written()
+		if len(vp8Headers) >= 2 && len(h264Headers) >= 2 {
+			break
+		}
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		input <- MediaReleasePair[image.Image]{img, nil}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, tc := range []struct {
+		writer      *lockedTrackLocalWriter
+		payloadType uint8
+		payload     []byte
+	}{
+		{&vp8Writer, 96, []byte{0x10, 1}},
+		{&h264Writer, 102, []byte{0x41, 2}},
+	} {
+		headers, payloads := tc.writer.written()
+		for i, header := range headers {
+			test.That(t, header.PayloadType, test.ShouldEqual, tc.payloadType)
+			test.That(t, payloads[i], test.ShouldResemble, tc.payload)
+		}
+	}
+	av1Headers, _ := av1Writer.written()
+	test.That(t, av1Headers, test.ShouldBeEmpty)
+}
+
+// frameAudioEncoderFactory makes encoders that, like Opus, only accept chunks that are
+// exactly one frame long. Each packet is the first sample of its frame.
+type frameAudioEncoderFactory struct{}
+
+func (f *frameAudioEncoderFactory) New(
+	sampleRate, channelCount int,
+	latency time.Duration,
+	opts codec.AudioEncoderOptions,
+	logger golog.Logger,
+) (codec.AudioEncoder, error) {
+	return &frameAudioEncoder{frameSize: int(time.Duration(sampleRate) * latency / time.Second)}, nil
+}
+
+func (f *frameAudioEncoderFactory) MIMEType() string {
+	return webrtc.MimeTypeOpus
+}
+
+type frameAudioEncoder struct {
+	frameSize int
+}
+
+func (e *frameAudioEncoder) Encode(ctx context.Context, chunk wave.Audio) ([]byte, bool, error) {
+	if chunk.ChunkInfo().Len != e.frameSize {
+		return nil, false, errors.Errorf("expected chunk of %d samples but got %d", e.frameSize, chunk.ChunkInfo().Len)
+	}
+	sample := chunk.At(0, 0).(wave.Int16Sample)
+	return []byte{byte(sample >> 8), byte(sample)}, true, nil
+}
+
+func (e *frameAudioEncoder) Close() {}
+
+func TestAudioChunksRechunked(t *testing.T) {
+	stream, err := NewStream(StreamConfig{
+		AudioEncoderFactory: &frameAudioEncoderFactory{},
+		Logger:              golog.NewTestLogger(t),
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	track, ok := stream.AudioTrackLocal()
+	test.That(t, ok, test.ShouldBeTrue)
+	var writer lockedTrackLocalWriter
+	_, err = track.Bind(&fakeTrackLocalContext{id: "peer", ssrc: 1, writeStream: &writer, codecs: []webrtc.RTPCodecParameters{{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+		PayloadType:        111,
+	}}})
+	test.That(t, err, test.ShouldBeNil)
+
+	stream.Start()
+	defer stream.Stop()
+	input, err := stream.InputAudioChunks(prop.Audio{Latency: AudioFrameDuration20ms})
+	test.That(t, err, test.ShouldBeNil)
+
+	// 7ms chunks at 48kHz add up to 7 frames of 20ms.
+	var next int16
+	for i := 0; i < 20; i++ {
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 336, Channels: 1, SamplingRate: 48000})
+		for j := 0; j < 336; j++ {
+			chunk.SetInt16(j, 0, wave.Int16Sample(next))
+			next++
+		}
+		input <- MediaReleasePair[wave.Audio]{chunk, nil}
+	}
+
+	var payloads [][]byte
+	for deadline := time.Now().Add(time.Second); len(payloads) < 7; _, payloads = writer.written() {
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond)
+	}
+	test.That(t, payloads, test.ShouldHaveLength, 7)
+	for i, payload := range payloads {
+		test.That(t, int(payload[0])<<8|int(payload[1]), test.ShouldEqual, i*960)
+	}
+}
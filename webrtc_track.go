@@ -1,11 +1,13 @@
 package gostream
 
 import (
-	"math"
+	"fmt"
+	"math/rand"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/webrtc/v3"
@@ -25,21 +27,132 @@ type trackBinding struct {
 	ssrc        webrtc.SSRC
 	payloadType webrtc.PayloadType
 	writeStream webrtc.TrackLocalWriter
+	// codecIdx is the index of the track codec negotiated for this binding.
+	codecIdx  int
+	clockRate uint32
+	// maxTemporalLayer is the highest temporal layer sent to this binding, or negative
+	// to send all layers. pendingMaxTemporalLayer replaces it at the next base layer packet
+	// since only then can the peer decode what follows.
+	maxTemporalLayer        int
+	pendingMaxTemporalLayer int
+	// droppedPackets is how many packets were not sent to this binding so that the sequence
+	// numbers it receives stay contiguous and dropped layers are not mistaken for loss.
+	droppedPackets uint16
+	// muted bindings are sent nothing while they stay negotiated.
+	muted bool
+	// parameterSetsSent is whether or not the binding was sent the parameter sets of the
+	// video, if it has any, so that it can decode what follows.
+	parameterSetsSent bool
+	// packetizer packetizes samples for this binding alone when bound to a
+	// trackLocalStaticSample so that every peer gets its own sequence numbers and timestamps.
+	packetizer *samplePacketizer
+	// history holds recently sent packets to answer NACKs with, if enabled for the track.
+	history *packetHistory
+	// rtx retransmits packets as RTX, if the peer negotiated it and history is enabled.
+	rtx *rtxEncoder
+	// rewriter gives this binding an RTP stream of its own when the track relays RTP.
+	rewriter *rtpRewriter
+	// fec protects what is sent to this binding with forward error correction, if enabled
+	// for the track and negotiated by the peer.
+	fec *fecEncoder
+	// packetsSent and bytesSent count everything sent to this binding, including retransmissions.
+	packetsSent uint64
+	bytesSent   uint64
+	// reportedPackets and reportedOctets count the packets sent on the binding's own SSRC and
+	// their payload bytes, as its sender reports do.
+	reportedPackets uint32
+	reportedOctets  uint32
 }
 
-// trackLocalStaticRTP  is a TrackLocal that has a pre-set codec and accepts RTP Packets.
+// write sends the given packet to the binding, along with any forward error correction,
+// and keeps what was sent for retransmission.
+func (b *trackBinding) write(header *rtp.Header, payload []byte) error {
+	if b.fec == nil {
+		return b.writePacket(header, payload)
+	}
+	packets, err := b.fec.protect(header, payload)
+	if err != nil {
+		return err
+	}
+	for i := range packets {
+		if err := b.writePacket(&packets[i].Header, packets[i].Payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *trackBinding) writePacket(header *rtp.Header, payload []byte) error {
+	if err := b.send(header, payload); err != nil {
+		return err
+	}
+	if b.history != nil {
+		b.history.add(header, payload)
+	}
+	return nil
+}
+
+// send sends the given packet to the binding and counts it.
+func (b *trackBinding) send(header *rtp.Header, payload []byte) error {
+	if _, err := b.writeStream.WriteRTP(header, payload); err != nil {
+		return err
+	}
+	b.packetsSent++
+	b.bytesSent += uint64(header.MarshalSize() + len(payload))
+	if header.SSRC == uint32(b.ssrc) {
+		b.reportedPackets++
+		b.reportedOctets += uint32(len(payload))
+	}
+	return nil
+}
+
+// bindingStats are what a track knows about what it sent to a single binding.
+type bindingStats struct {
+	mimeType    string
+	clockRate   uint32
+	packetsSent uint64
+	bytesSent   uint64
+}
+
+// acceptsTemporalLayer returns whether or not media of the given temporal layer should be
+// sent to the binding. A pending layer limit takes effect on base layer media.
+func (b *trackBinding) acceptsTemporalLayer(temporalLayer int) bool {
+	if temporalLayer == 0 {
+		b.maxTemporalLayer = b.pendingMaxTemporalLayer
+	}
+	return b.maxTemporalLayer < 0 || temporalLayer <= b.maxTemporalLayer
+}
+
+// trackLocalStaticRTP  is a TrackLocal that has a pre-set list of codecs and accepts RTP Packets.
 // If you wish to send a media.Sample use trackLocalStaticSample.
 type trackLocalStaticRTP struct {
-	mu                sync.RWMutex
-	bindings          []trackBinding
-	codec             webrtc.RTPCodecCapability
+	mu       sync.RWMutex
+	bindings []trackBinding
+	// codecs are the codecs this track can send in order of preference.
+	codecs            []webrtc.RTPCodecCapability
 	id, rid, streamID string
+	// nackHistorySize is how many sent packets each binding keeps for retransmission.
+	// Zero disables retransmission.
+	nackHistorySize int
+	// fecOverhead is the fraction of packets sent as forward error correction to bindings
+	// that negotiated it. Zero disables forward error correction.
+	fecOverhead float64
+	// rewrite gives every binding its own sequence numbers and timestamps instead of those
+	// of the packets written.
+	rewrite bool
+	// onBind, if set, is called after every new binding, such as to request a key frame so
+	// that the new peer can start decoding right away.
+	onBind func()
+	// onUnbind, if set, is called with the SSRC of every binding removed, such as to forget
+	// what is known about the peer.
+	onUnbind func(ssrc webrtc.SSRC)
 }
 
-// newtrackLocalStaticRTP returns a trackLocalStaticRTP.
-func newtrackLocalStaticRTP(c webrtc.RTPCodecCapability, id, streamID string) *trackLocalStaticRTP {
+// newtrackLocalStaticRTP returns a trackLocalStaticRTP that offers the given codecs in order
+// of preference.
+func newtrackLocalStaticRTP(codecs []webrtc.RTPCodecCapability, id, streamID string) *trackLocalStaticRTP {
 	return &trackLocalStaticRTP{
-		codec:    c,
+		codecs:   codecs,
 		bindings: []trackBinding{},
 		id:       id,
 		streamID: streamID,
@@ -50,38 +163,187 @@ func newtrackLocalStaticRTP(c webrtc.RTPCodecCapability, id, streamID string) *t
 // This asserts that the code requested is supported by the remote peer.
 // If so it setups all the state (SSRC and PayloadType) to have a call.
 func (s *trackLocalStaticRTP) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	codec, _, err := s.bind(t)
+	if err == nil && s.onBind != nil {
+		s.onBind()
+	}
+	return codec, err
+}
+
+// bind binds the most preferred codec that the remote peer supports and returns
+// its index in the track's codecs.
+func (s *trackLocalStaticRTP) bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	parameters := webrtc.RTPCodecParameters{RTPCodecCapability: s.codec}
-	if codec, err := codecParametersFuzzySearch(parameters, t.CodecParameters()); err == nil {
-		s.bindings = append(s.bindings, trackBinding{
-			ssrc:        t.SSRC(),
-			payloadType: codec.PayloadType,
-			writeStream: t.WriteStream(),
-			id:          t.ID(),
-		})
-		return codec, nil
+	for codecIdx, c := range s.codecs {
+		parameters := webrtc.RTPCodecParameters{RTPCodecCapability: c}
+		if codec, err := codecParametersFuzzySearch(parameters, t.CodecParameters()); err == nil {
+			s.bindings = append(s.bindings, trackBinding{
+				ssrc:        t.SSRC(),
+				payloadType: codec.PayloadType,
+				writeStream: t.WriteStream(),
+				id:          t.ID(),
+				codecIdx:    codecIdx,
+				clockRate:   codec.ClockRate,
+				// all layers are sent until limited.
+				maxTemporalLayer:        -1,
+				pendingMaxTemporalLayer: -1,
+			})
+			if s.nackHistorySize > 0 {
+				s.bindings[len(s.bindings)-1].history = newPacketHistory(s.nackHistorySize)
+				s.bindings[len(s.bindings)-1].rtx = negotiateRTX(t.CodecParameters(), t.SSRC())
+				codec.RTCPFeedback = withoutGenericNACK(codec.RTCPFeedback)
+			}
+			if s.rewrite {
+				s.bindings[len(s.bindings)-1].rewriter = &rtpRewriter{}
+			}
+			if s.fecOverhead > 0 {
+				s.bindings[len(s.bindings)-1].fec = negotiateFEC(t.CodecParameters(), s.fecOverhead)
+			}
+			return codec, codecIdx, nil
+		}
+	}
+
+	return webrtc.RTPCodecParameters{}, 0, webrtc.ErrUnsupportedCodec
+}
+
+// negotiateFEC returns an fecEncoder if the given negotiated codecs allow for forward error
+// correction.
+func negotiateFEC(negotiated []webrtc.RTPCodecParameters, overhead float64) *fecEncoder {
+	red, err := codecParametersFuzzySearch(
+		webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypeRED}},
+		negotiated,
+	)
+	if err != nil {
+		return nil
+	}
+	ulpfec, err := codecParametersFuzzySearch(
+		webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypeULPFEC}},
+		negotiated,
+	)
+	if err != nil {
+		return nil
 	}
+	return newFECEncoder(red.PayloadType, ulpfec.PayloadType, overhead)
+}
 
-	return webrtc.RTPCodecParameters{}, webrtc.ErrUnsupportedCodec
+// codecInUse returns whether or not any binding that is not muted negotiated the codec of
+// the given index.
+func (s *trackLocalStaticRTP) codecInUse(codecIdx int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, b := range s.bindings {
+		if b.codecIdx == codecIdx && !b.muted {
+			return true
+		}
+	}
+	return false
+}
+
+// setMuted mutes or unmutes the binding with the given SSRC.
+func (s *trackLocalStaticRTP) setMuted(ssrc webrtc.SSRC, muted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.bindings {
+		if s.bindings[i].ssrc == ssrc {
+			s.bindings[i].muted = muted
+			return nil
+		}
+	}
+	return fmt.Errorf("no binding with SSRC %d", ssrc)
+}
+
+// bindingStats returns what was sent to the binding with the given SSRC.
+func (s *trackLocalStaticRTP) bindingStats(ssrc webrtc.SSRC) (bindingStats, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, b := range s.bindings {
+		if b.ssrc == ssrc {
+			return bindingStats{
+				mimeType:    s.codecs[b.codecIdx].MimeType,
+				clockRate:   b.clockRate,
+				packetsSent: b.packetsSent,
+				bytesSent:   b.bytesSent,
+			}, true
+		}
+	}
+	return bindingStats{}, false
+}
+
+// setMaxTemporalLayer limits the binding with the given SSRC to temporal layers up to
+// and including the given one. A negative layer removes the limit.
+func (s *trackLocalStaticRTP) setMaxTemporalLayer(ssrc webrtc.SSRC, layer int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.bindings {
+		if s.bindings[i].ssrc == ssrc {
+			s.bindings[i].pendingMaxTemporalLayer = layer
+			return nil
+		}
+	}
+	return fmt.Errorf("no binding with SSRC %d", ssrc)
+}
+
+// retransmit sends the packets with the given sequence numbers to the binding with the
+// given SSRC again, as RTX if the binding negotiated it. Packets no longer kept are skipped
+// since the viewer will eventually ask for a key frame instead.
+func (s *trackLocalStaticRTP) retransmit(ssrc webrtc.SSRC, sequenceNumbers []uint16) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sent int
+	writeErrs := []error{}
+	for i := range s.bindings {
+		b := &s.bindings[i]
+		if b.ssrc != ssrc || b.history == nil {
+			continue
+		}
+		for _, sequenceNumber := range sequenceNumbers {
+			header, payload, ok := b.history.get(sequenceNumber)
+			if !ok {
+				continue
+			}
+			if b.rtx != nil {
+				if rtxHeader, rtxPayload, ok := b.rtx.encode(header, payload); ok {
+					header, payload = &rtxHeader, rtxPayload
+				}
+			}
+			if err := b.send(header, payload); err != nil {
+				writeErrs = append(writeErrs, err)
+				continue
+			}
+			sent++
+		}
+	}
+	return sent, multierr.Combine(writeErrs...)
 }
 
 // Unbind implements the teardown logic when the track is no longer needed. This happens
 // because a track has been stopped.
 func (s *trackLocalStaticRTP) Unbind(t webrtc.TrackLocalContext) error {
+	ssrc, err := s.unbind(t)
+	if err == nil && s.onUnbind != nil {
+		s.onUnbind(ssrc)
+	}
+	return err
+}
+
+// unbind removes the binding of the given context and returns its SSRC.
+func (s *trackLocalStaticRTP) unbind(t webrtc.TrackLocalContext) (webrtc.SSRC, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for i := range s.bindings {
 		if s.bindings[i].id == t.ID() {
+			ssrc := s.bindings[i].ssrc
 			s.bindings[i] = s.bindings[len(s.bindings)-1]
 			s.bindings = s.bindings[:len(s.bindings)-1]
-			return nil
+			return ssrc, nil
 		}
 	}
 
-	return webrtc.ErrUnbindFailed
+	return 0, webrtc.ErrUnbindFailed
 }
 
 // ID is the unique identifier for this Track. This should be unique for the
@@ -98,18 +360,18 @@ func (s *trackLocalStaticRTP) StreamID() string { return s.streamID }
 // Kind controls if this TrackLocal is audio or video.
 func (s *trackLocalStaticRTP) Kind() webrtc.RTPCodecType {
 	switch {
-	case strings.HasPrefix(s.codec.MimeType, "audio/"):
+	case strings.HasPrefix(s.codecs[0].MimeType, "audio/"):
 		return webrtc.RTPCodecTypeAudio
-	case strings.HasPrefix(s.codec.MimeType, "video/"):
+	case strings.HasPrefix(s.codecs[0].MimeType, "video/"):
 		return webrtc.RTPCodecTypeVideo
 	default:
 		return webrtc.RTPCodecType(0)
 	}
 }
 
-// Codec gets the Codec of the track.
+// Codec gets the most preferred Codec of the track.
 func (s *trackLocalStaticRTP) Codec() webrtc.RTPCodecCapability {
-	return s.codec
+	return s.codecs[0]
 }
 
 // WriteRTP writes a RTP Packet to the trackLocalStaticRTP
@@ -117,16 +379,43 @@ func (s *trackLocalStaticRTP) Codec() webrtc.RTPCodecCapability {
 // all PeerConnections. The error message will contain the ID of the failed
 // PeerConnections so you can remove them.
 func (s *trackLocalStaticRTP) WriteRTP(p *rtp.Packet) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.writeRTP(p, -1, 0)
+}
+
+// writeRTP writes a RTP Packet belonging to the given temporal layer to all bindings that
+// negotiated the codec of the given index, or to all bindings if the index is negative.
+// Bindings limited to lower temporal layers skip the packet.
+func (s *trackLocalStaticRTP) writeRTP(p *rtp.Packet, codecIdx, temporalLayer int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	writeErrs := []error{}
 	outboundPacket := *p
 
-	for _, b := range s.bindings {
+	for i := range s.bindings {
+		b := &s.bindings[i]
+		if codecIdx >= 0 && b.codecIdx != codecIdx {
+			continue
+		}
+		if b.muted {
+			b.droppedPackets++
+			continue
+		}
+		if !b.acceptsTemporalLayer(temporalLayer) {
+			b.droppedPackets++
+			continue
+		}
+		sequenceNumber := p.SequenceNumber
+		if b.rewriter != nil {
+			sequenceNumber, outboundPacket.Header.Timestamp = b.rewriter.rewrite(&p.Header, b.clockRate)
+			// extension IDs are negotiated with each peer so the source's cannot be relayed.
+			outboundPacket.Header.Extension = false
+			outboundPacket.Header.Extensions = nil
+		}
+		outboundPacket.Header.SequenceNumber = sequenceNumber - b.droppedPackets
 		outboundPacket.Header.SSRC = uint32(b.ssrc)
 		outboundPacket.Header.PayloadType = uint8(b.payloadType)
-		if _, err := b.writeStream.WriteRTP(&outboundPacket.Header, outboundPacket.Payload); err != nil {
+		if err := b.write(&outboundPacket.Header, outboundPacket.Payload); err != nil {
 			writeErrs = append(writeErrs, err)
 		}
 	}
@@ -147,32 +436,114 @@ func (s *trackLocalStaticRTP) Write(b []byte) (n int, err error) {
 	return len(b), s.WriteRTP(packet)
 }
 
-// trackLocalStaticSample is a TrackLocal that has a pre-set codec and accepts Samples.
+// trackLocalStaticSample is a TrackLocal that has a pre-set list of codecs and accepts Samples.
 // If you wish to send a RTP Packet use trackLocalStaticRTP.
 type trackLocalStaticSample struct {
-	packetizer   rtp.Packetizer
-	rtpTrack     *trackLocalStaticRTP
-	sampler      samplerFunc
-	isAudio      bool
-	clockRate    uint32
-	audioLatency time.Duration
+	rtpTrack *trackLocalStaticRTP
+	isAudio  bool
+	// clock is the stream's media clock that the timestamps of every binding follow.
+	clock *mediaClock
+	// audioLatency is how long each audio chunk lasts. nextAudioTimes holds the media time of
+	// the next chunk of each codec. Both are guarded by the mutex of rtpTrack.
+	audioLatency   time.Duration
+	nextAudioTimes []time.Time
+	// handoffs holds the state to continue with when the binding of a given SSRC is next
+	// made. It is guarded by the mutex of rtpTrack.
+	handoffs map[webrtc.SSRC]bindingHandoff
+	// parameterSets holds the latest parameter sets of each codec, or nil for codecs without
+	// any. It is guarded by the mutex of rtpTrack.
+	parameterSets []*parameterSets
+}
+
+// bindingHandoff is the state a peer's binding carries over to another track when the peer
+// is switched to that track. Continuing with the same packetizer keeps the sequence numbers
+// and timestamps the peer receives contiguous.
+type bindingHandoff struct {
+	codecIdx        int
+	packetizer      *samplePacketizer
+	history         *packetHistory
+	rtx             *rtxEncoder
+	fec             *fecEncoder
+	packetsSent     uint64
+	bytesSent       uint64
+	reportedPackets uint32
+	reportedOctets  uint32
+	muted           bool
+}
+
+// samplePacketizer packetizes samples for a single binding. The timestamps of the packets
+// are the time of the stream's media clock at the binding's clock rate, starting at a random
+// offset like a new RTP stream would.
+type samplePacketizer struct {
+	packetizer      rtp.Packetizer
+	clockRate       uint32
+	timestampOffset uint32
+}
+
+// newSamplePacketizer returns a samplePacketizer for a binding with the given SSRC that
+// negotiated the given codec. Payloaders keep state in between packets so every binding
+// needs its own.
+func newSamplePacketizer(codec webrtc.RTPCodecParameters, ssrc webrtc.SSRC) (*samplePacketizer, error) {
+	payloader, err := payloaderForCodec(codec.RTPCodecCapability)
+	if err != nil {
+		return nil, err
+	}
+	return &samplePacketizer{
+		packetizer: rtp.NewPacketizer(
+			rtpOutboundMTU,
+			uint8(codec.PayloadType),
+			uint32(ssrc),
+			payloader,
+			rtp.NewRandomSequencer(),
+			codec.ClockRate,
+		),
+		clockRate: codec.ClockRate,
+		//nolint:gosec
+		timestampOffset: rand.Uint32(),
+	}, nil
+}
+
+// timestamp returns the RTP timestamp of the given media time.
+func (p *samplePacketizer) timestamp(clock *mediaClock, mediaTime time.Time) uint32 {
+	return p.timestampOffset + clock.rtpTime(mediaTime, p.clockRate)
 }
 
-// newVideoTrackLocalStaticSample returns a trackLocalStaticSample for video.
-func newVideoTrackLocalStaticSample(c webrtc.RTPCodecCapability, id, streamID string) *trackLocalStaticSample {
+// newVideoTrackLocalStaticSample returns a trackLocalStaticSample for video tagged with
+// the given RID that keeps the given number of sent packets per binding for retransmission
+// and sends the given fraction of forward error correction.
+func newVideoTrackLocalStaticSample(
+	codecs []webrtc.RTPCodecCapability,
+	id, rid, streamID string,
+	clock *mediaClock,
+	nackHistorySize int,
+	fecOverhead float64,
+) *trackLocalStaticSample {
+	rtpTrack := newtrackLocalStaticRTP(codecs, id, streamID)
+	rtpTrack.rid = rid
+	rtpTrack.nackHistorySize = nackHistorySize
+	rtpTrack.fecOverhead = fecOverhead
+	parameterSets := make([]*parameterSets, 0, len(codecs))
+	for _, c := range codecs {
+		parameterSets = append(parameterSets, newParameterSets(c.MimeType))
+	}
 	return &trackLocalStaticSample{
-		rtpTrack: newtrackLocalStaticRTP(c, id, streamID),
+		rtpTrack:      rtpTrack,
+		clock:         clock,
+		parameterSets: parameterSets,
 	}
 }
 
 // newAudioTrackLocalStaticSample returns a trackLocalStaticSample for audio.
 func newAudioTrackLocalStaticSample(
-	c webrtc.RTPCodecCapability,
+	codecs []webrtc.RTPCodecCapability,
 	id, streamID string,
+	clock *mediaClock,
 ) *trackLocalStaticSample {
 	return &trackLocalStaticSample{
-		rtpTrack: newtrackLocalStaticRTP(c, id, streamID),
-		isAudio:  true,
+		rtpTrack:       newtrackLocalStaticRTP(codecs, id, streamID),
+		isAudio:        true,
+		clock:          clock,
+		nextAudioTimes: make([]time.Time, len(codecs)),
 	}
 }
 
@@ -190,7 +561,7 @@ func (s *trackLocalStaticSample) RID() string { return s.rtpTrack.RID() }
 // Kind controls if this TrackLocal is audio or video.
 func (s *trackLocalStaticSample) Kind() webrtc.RTPCodecType { return s.rtpTrack.Kind() }
 
-// Codec gets the Codec of the track.
+// Codec gets the most preferred Codec of the track.
 func (s *trackLocalStaticSample) Codec() webrtc.RTPCodecCapability {
 	return s.rtpTrack.Codec()
 }
@@ -199,45 +570,180 @@ const rtpOutboundMTU = 1200
 
 // Bind is called by the PeerConnection after negotiation is complete
 // This asserts that the code requested is supported by the remote peer.
-// If so it setups all the state (SSRC and PayloadType) to have a call.
+// If so it setups all the state (SSRC, PayloadType and packetizer) to have a call.
 func (s *trackLocalStaticSample) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
-	codec, err := s.rtpTrack.Bind(t)
+	codec, codecIdx, err := s.rtpTrack.bind(t)
 	if err != nil {
 		return codec, err
 	}
 
 	s.rtpTrack.mu.Lock()
-	defer s.rtpTrack.mu.Unlock()
+	handoff, handedOff := s.handoffs[t.SSRC()]
+	delete(s.handoffs, t.SSRC())
+	s.rtpTrack.mu.Unlock()
 
-	// We only need one packetizer. But isn't that confusing with other clock rates
-	// from other codecs?
-	if s.packetizer != nil {
-		return codec, nil
+	// a handoff can only be continued with if the peer negotiated the same codec.
+	handedOff = handedOff && handoff.codecIdx == codecIdx
+	packetizer := handoff.packetizer
+	if !handedOff {
+		packetizer, err = newSamplePacketizer(codec, t.SSRC())
+		if err != nil {
+			return codec, multierr.Combine(err, s.rtpTrack.Unbind(t))
+		}
 	}
 
-	payloader, err := payloaderForCodec(codec.RTPCodecCapability)
-	if err != nil {
-		return codec, err
+	s.rtpTrack.mu.Lock()
+	for i := range s.rtpTrack.bindings {
+		b := &s.rtpTrack.bindings[i]
+		if b.id != t.ID() {
+			continue
+		}
+		b.packetizer = packetizer
+		if !handedOff {
+			continue
+		}
+		if b.history != nil && handoff.history != nil {
+			b.history = handoff.history
+		}
+		// the peer keeps following the sequence numbers of the RTX stream it was sent.
+		if b.rtx != nil && handoff.rtx != nil {
+			b.rtx = handoff.rtx
+		}
+		// the FEC packets sent so far shift the sequence numbers the peer expects.
+		if b.fec != nil && handoff.fec != nil {
+			b.fec = handoff.fec
+		}
+		b.packetsSent = handoff.packetsSent
+		b.bytesSent = handoff.bytesSent
+		b.reportedPackets = handoff.reportedPackets
+		b.reportedOctets = handoff.reportedOctets
+		b.muted = handoff.muted
 	}
+	s.rtpTrack.mu.Unlock()
 
-	// TODO(erd): I think we need to do this for each bind
-	s.packetizer = rtp.NewPacketizer(
-		rtpOutboundMTU,
-		uint8(codec.PayloadType),
-		uint32(t.SSRC()),
-		payloader,
-		rtp.NewRandomSequencer(),
-		codec.ClockRate,
-	)
-
-	s.clockRate = codec.RTPCodecCapability.ClockRate
+	if s.rtpTrack.onBind != nil {
+		s.rtpTrack.onBind()
+	}
 	return codec, nil
 }
 
+// handoff returns the state of the binding with the given SSRC so that another track can
+// continue with it.
+func (s *trackLocalStaticSample) handoff(ssrc webrtc.SSRC) (bindingHandoff, bool) {
+	s.rtpTrack.mu.RLock()
+	defer s.rtpTrack.mu.RUnlock()
+	for _, b := range s.rtpTrack.bindings {
+		if b.ssrc == ssrc && b.packetizer != nil {
+			return bindingHandoff{
+				codecIdx:        b.codecIdx,
+				packetizer:      b.packetizer,
+				history:         b.history,
+				rtx:             b.rtx,
+				fec:             b.fec,
+				packetsSent:     b.packetsSent,
+				bytesSent:       b.bytesSent,
+				reportedPackets: b.reportedPackets,
+				reportedOctets:  b.reportedOctets,
+				muted:           b.muted,
+			}, true
+		}
+	}
+	return bindingHandoff{}, false
+}
+
+// senderReport returns a sender report for the binding with the given SSRC that relates the
+// stream's media clock at the given time to the binding's RTP timestamps.
+func (s *trackLocalStaticSample) senderReport(ssrc webrtc.SSRC, now time.Time) (*rtcp.SenderReport, bool) {
+	s.rtpTrack.mu.RLock()
+	defer s.rtpTrack.mu.RUnlock()
+	for _, b := range s.rtpTrack.bindings {
+		if b.ssrc != ssrc || b.packetizer == nil {
+			continue
+		}
+		return &rtcp.SenderReport{
+			SSRC:        uint32(ssrc),
+			NTPTime:     ntpTime(now),
+			RTPTime:     b.packetizer.timestamp(s.clock, now),
+			PacketCount: b.reportedPackets,
+			OctetCount:  b.reportedOctets,
+		}, true
+	}
+	return nil, false
+}
+
+// expectHandoff makes the next binding with the given SSRC continue with the given state.
+func (s *trackLocalStaticSample) expectHandoff(ssrc webrtc.SSRC, handoff bindingHandoff) {
+	s.rtpTrack.mu.Lock()
+	defer s.rtpTrack.mu.Unlock()
+	if s.handoffs == nil {
+		s.handoffs = map[webrtc.SSRC]bindingHandoff{}
+	}
+	s.handoffs[ssrc] = handoff
+}
+
+// expectsHandoff returns whether or not a handoff is expected for the given SSRC.
+func (s *trackLocalStaticSample) expectsHandoff(ssrc webrtc.SSRC) bool {
+	s.rtpTrack.mu.Lock()
+	defer s.rtpTrack.mu.Unlock()
+	_, ok := s.handoffs[ssrc]
+	return ok
+}
+
+// cancelHandoff forgets a handoff expected for the given SSRC.
+func (s *trackLocalStaticSample) cancelHandoff(ssrc webrtc.SSRC) {
+	s.rtpTrack.mu.Lock()
+	defer s.rtpTrack.mu.Unlock()
+	delete(s.handoffs, ssrc)
+}
+
 func (s *trackLocalStaticSample) setAudioLatency(latency time.Duration) {
 	s.rtpTrack.mu.Lock()
 	defer s.rtpTrack.mu.Unlock()
 	s.audioLatency = latency
+	for codecIdx := range s.nextAudioTimes {
+		s.nextAudioTimes[codecIdx] = time.Time{}
+	}
+}
+
+// audioTime returns the media time of the next audio chunk of the given codec captured at
+// the given time. Chunks follow each other exactly so that the timestamps advance evenly,
+// unless they drift too far from when they were captured, such as after a pause. It must be
+// called with the mutex of rtpTrack held.
+func (s *trackLocalStaticSample) audioTime(codecIdx int, capturedAt time.Time) time.Time {
+	mediaTime := s.nextAudioTimes[codecIdx]
+	if drift := capturedAt.Sub(mediaTime); mediaTime.IsZero() || drift > maxAudioClockDrift || drift < -maxAudioClockDrift {
+		mediaTime = capturedAt
+	}
+	s.nextAudioTimes[codecIdx] = mediaTime.Add(s.audioLatency)
+	return mediaTime
+}
+
+// codecInUse returns whether or not any binding that is not muted negotiated the codec of
+// the given index.
+func (s *trackLocalStaticSample) codecInUse(codecIdx int) bool {
+	return s.rtpTrack.codecInUse(codecIdx)
+}
+
+// setMuted mutes or unmutes the binding with the given SSRC.
+func (s *trackLocalStaticSample) setMuted(ssrc webrtc.SSRC, muted bool) error {
+	return s.rtpTrack.setMuted(ssrc, muted)
+}
+
+// setMaxTemporalLayer limits the binding with the given SSRC to temporal layers up to
+// and including the given one. A negative layer removes the limit.
+func (s *trackLocalStaticSample) setMaxTemporalLayer(ssrc webrtc.SSRC, layer int) error {
+	return s.rtpTrack.setMaxTemporalLayer(ssrc, layer)
+}
+
+// bindingStats returns what was sent to the binding with the given SSRC.
+func (s *trackLocalStaticSample) bindingStats(ssrc webrtc.SSRC) (bindingStats, bool) {
+	return s.rtpTrack.bindingStats(ssrc)
+}
+
+// retransmit sends the packets with the given sequence numbers to the binding with the
+// given SSRC again.
+func (s *trackLocalStaticSample) retransmit(ssrc webrtc.SSRC, sequenceNumbers []uint16) (int, error) {
+	return s.rtpTrack.retransmit(ssrc, sequenceNumbers)
 }
 
 // Unbind implements the teardown logic when the track is no longer needed. This happens
@@ -246,41 +752,64 @@ func (s *trackLocalStaticSample) Unbind(t webrtc.TrackLocalContext) error {
 	return s.rtpTrack.Unbind(t)
 }
 
-// WriteData writes already encoded data to the trackLocalStaticSample
+// WriteData writes data already encoded with the codec of the given index to the
+// trackLocalStaticSample. It is packetized separately for every binding that negotiated
+// that codec and accepts the given temporal layer, and timestamped with the given time at
+// which the media was captured rather than when it is written so that encoding and queueing
+// delays do not show in the timestamps.
 // If one PeerConnection fails the packets will still be sent to
 // all PeerConnections. The error message will contain the ID of the failed
 // PeerConnections so you can remove them.
-func (s *trackLocalStaticSample) WriteData(frame []byte) error {
+func (s *trackLocalStaticSample) WriteData(codecIdx int, frame []byte, temporalLayer int, capturedAt time.Time) error {
 	s.rtpTrack.mu.Lock()
-	p := s.packetizer
-	if p == nil {
-		s.rtpTrack.mu.Unlock()
-		return nil
-	}
+	defer s.rtpTrack.mu.Unlock()
 	if s.isAudio && s.audioLatency == 0 {
 		return nil
 	}
-	sampler := s.sampler
-	if sampler == nil {
-		if s.isAudio {
-			s.sampler = newAudioSampler(s.clockRate, s.audioLatency)
-		} else {
-			s.sampler = newVideoSampler(s.clockRate)
-		}
-	}
 
-	s.rtpTrack.mu.Unlock()
+	mediaTime := capturedAt
+	if s.isAudio {
+		mediaTime = s.audioTime(codecIdx, capturedAt)
+	}
 
-	if s.sampler == nil {
-		return nil
+	var sets *parameterSets
+	var hasParameterSets, keyFrame bool
+	if codecIdx < len(s.parameterSets) {
+		sets = s.parameterSets[codecIdx]
+	}
+	if sets != nil {
+		hasParameterSets, keyFrame = sets.update(frame)
 	}
-	samples := s.sampler()
-	packets := p.Packetize(frame, samples)
 
 	writeErrs := []error{}
-	for _, p := range packets {
-		if err := s.rtpTrack.WriteRTP(p); err != nil {
-			writeErrs = append(writeErrs, err)
+	for i := range s.rtpTrack.bindings {
+		b := &s.rtpTrack.bindings[i]
+		// a binding has no packetizer for the brief moment in between being bound and Bind returning.
+		if b.codecIdx != codecIdx || b.packetizer == nil || b.muted {
+			continue
+		}
+		// frames skipped this way never reach the packetizer so sequence numbers stay contiguous.
+		if !b.acceptsTemporalLayer(temporalLayer) {
+			continue
+		}
+		data := frame
+		// a binding that joined after the parameter sets were sent gets the cached ones ahead
+		// of its first key frame.
+		if sets != nil && !b.parameterSetsSent {
+			switch {
+			case hasParameterSets:
+				b.parameterSetsSent = true
+			case keyFrame:
+				data = sets.prepend(frame)
+				b.parameterSetsSent = true
+			}
+		}
+		timestamp := b.packetizer.timestamp(s.clock, mediaTime)
+		for _, packet := range b.packetizer.packetizer.Packetize(data, 0) {
+			packet.Header.Timestamp = timestamp
+			if err := b.write(&packet.Header, packet.Payload); err != nil {
+				writeErrs = append(writeErrs, err)
+			}
 		}
 	}
 
@@ -312,6 +841,10 @@ func payloaderForCodec(codec webrtc.RTPCodecCapability) (rtp.Payloader, error) {
 	switch strings.ToLower(codec.MimeType) {
 	case strings.ToLower(webrtc.MimeTypeH264):
 		return &codecs.H264Payloader{}, nil
+	case strings.ToLower(webrtc.MimeTypeH265):
+		return &h265Payloader{}, nil
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		return &av1Payloader{}, nil
 	case strings.ToLower(webrtc.MimeTypeOpus):
 		return &codecs.OpusPayloader{}, nil
 	case strings.ToLower(webrtc.MimeTypeVP8):
@@ -326,29 +859,3 @@ func payloaderForCodec(codec webrtc.RTPCodecCapability) (rtp.Payloader, error) {
 		return nil, webrtc.ErrNoPayloaderForCodec
 	}
 }
-
-type samplerFunc func() uint32
-
-// newVideoSampler creates a video sampler that uses the actual video frame rate and
-// the codec's clock rate to come up with a duration for each sample.
-func newVideoSampler(clockRate uint32) samplerFunc {
-	clockRateFloat := float64(clockRate)
-	lastTimestamp := time.Now()
-
-	return samplerFunc(func() uint32 {
-		now := time.Now()
-		duration := now.Sub(lastTimestamp).Seconds()
-		samples := uint32(math.Round(clockRateFloat * duration))
-		lastTimestamp = now
-		return samples
-	})
-}
-
-// newAudioSampler creates a audio sampler that uses a fixed latency and
-// the codec's clock rate to come up with a duration for each sample.
-func newAudioSampler(clockRate uint32, latency time.Duration) samplerFunc {
-	samples := uint32(math.Round(float64(clockRate) * latency.Seconds()))
-	return samplerFunc(func() uint32 {
-		return samples
-	})
-}
@@ -0,0 +1,301 @@
+package gostream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.viam.com/test"
+)
+
+type fakeTrackLocalWriter struct {
+	headers  []rtp.Header
+	payloads [][]byte
+}
+
+func (w *fakeTrackLocalWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.headers = append(w.headers, *header)
+	w.payloads = append(w.payloads, append([]byte(nil), payload...))
+	return len(payload), nil
+}
+
+func (w *fakeTrackLocalWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestWriteRTPTemporalLayers(t *testing.T) {
+	track := newtrackLocalStaticRTP([]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8}}, "video", "stream")
+	var full, constrained fakeTrackLocalWriter
+	track.bindings = []trackBinding{
+		{ssrc: 1, writeStream: &full, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1},
+		{ssrc: 2, writeStream: &constrained, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1},
+	}
+
+	test.That(t, track.setMaxTemporalLayer(2, 0), test.ShouldBeNil)
+	test.That(t, track.setMaxTemporalLayer(3, 0), test.ShouldNotBeNil)
+
+	// the limit only applies from the next base layer packet onward.
+	layers := []int{1, 0, 1, 0, 0, 1}
+	for i, layer := range layers {
+		packet := &rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(100 + i)}}
+		test.That(t, track.writeRTP(packet, 0, layer), test.ShouldBeNil)
+	}
+
+	test.That(t, full.headers, test.ShouldHaveLength, len(layers))
+	for i, header := range full.headers {
+		test.That(t, header.SequenceNumber, test.ShouldEqual, 100+i)
+		test.That(t, header.SSRC, test.ShouldEqual, 1)
+	}
+
+	test.That(t, constrained.headers, test.ShouldHaveLength, 4)
+	for i, header := range constrained.headers {
+		test.That(t, header.SequenceNumber, test.ShouldEqual, 100+i)
+		test.That(t, header.SSRC, test.ShouldEqual, 2)
+	}
+
+	// removing the limit sends all layers again starting at the next base layer packet.
+	test.That(t, track.setMaxTemporalLayer(2, -1), test.ShouldBeNil)
+	test.That(t, track.writeRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 106}}, 0, 0), test.ShouldBeNil)
+	test.That(t, track.writeRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 107}}, 0, 1), test.ShouldBeNil)
+	test.That(t, constrained.headers, test.ShouldHaveLength, 6)
+	test.That(t, constrained.headers[5].SequenceNumber, test.ShouldEqual, 105)
+}
+
+func TestWriteDataPerBinding(t *testing.T) {
+	track := newAudioTrackLocalStaticSample([]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeOpus}}, "audio", "stream", newMediaClock())
+	track.setAudioLatency(20 * time.Millisecond)
+
+	var first, second fakeTrackLocalWriter
+	firstPacketizer, err := newSamplePacketizer(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000},
+		PayloadType:        111,
+	}, 1)
+	test.That(t, err, test.ShouldBeNil)
+	secondPacketizer, err := newSamplePacketizer(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 16000},
+		PayloadType:        109,
+	}, 2)
+	test.That(t, err, test.ShouldBeNil)
+	track.rtpTrack.bindings = []trackBinding{
+		{ssrc: 1, writeStream: &first, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1, packetizer: firstPacketizer},
+		{ssrc: 2, writeStream: &second, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1, packetizer: secondPacketizer},
+	}
+
+	for i := 0; i < 3; i++ {
+		test.That(t, track.WriteData(0, []byte{1, 2, 3}, 0, time.Now()), test.ShouldBeNil)
+	}
+
+	for _, tc := range []struct {
+		headers     []rtp.Header
+		ssrc        uint32
+		payloadType uint8
+		samples     uint32
+	}{
+		{first.headers, 1, 111, 960},
+		{second.headers, 2, 109, 320},
+	} {
+		test.That(t, tc.headers, test.ShouldHaveLength, 3)
+		for i, header := range tc.headers {
+			test.That(t, header.SSRC, test.ShouldEqual, tc.ssrc)
+			test.That(t, header.PayloadType, test.ShouldEqual, tc.payloadType)
+			if i > 0 {
+				test.That(t, header.SequenceNumber, test.ShouldEqual, tc.headers[i-1].SequenceNumber+1)
+				test.That(t, header.Timestamp-tc.headers[i-1].Timestamp, test.ShouldEqual, tc.samples)
+			}
+		}
+	}
+
+	// each binding owns its packetizer so unbinding one leaves the other untouched.
+	track.rtpTrack.bindings = track.rtpTrack.bindings[1:]
+	test.That(t, track.WriteData(0, []byte{1, 2, 3}, 0, time.Now()), test.ShouldBeNil)
+	test.That(t, first.headers, test.ShouldHaveLength, 3)
+	test.That(t, second.headers, test.ShouldHaveLength, 4)
+	test.That(t, second.headers[3].SequenceNumber, test.ShouldEqual, second.headers[2].SequenceNumber+1)
+}
+
+func TestRetransmit(t *testing.T) {
+	track := newtrackLocalStaticRTP([]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8}}, "video", "stream")
+	var withHistory, withoutHistory fakeTrackLocalWriter
+	track.bindings = []trackBinding{
+		{ssrc: 1, writeStream: &withHistory, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1, history: newPacketHistory(3)},
+		{ssrc: 2, writeStream: &withoutHistory, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1},
+	}
+
+	// the history is rounded up to 4 packets and wraps around with the sequence numbers.
+	for i := 0; i < 6; i++ {
+		packet := &rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(65533 + i)}, Payload: []byte{byte(i)}}
+		test.That(t, track.WriteRTP(packet), test.ShouldBeNil)
+	}
+
+	sent, err := track.retransmit(1, []uint16{65533, 65534, 65535, 0, 2, 3})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldEqual, 3)
+	test.That(t, withHistory.headers, test.ShouldHaveLength, 9)
+	for i, sequenceNumber := range []uint16{65535, 0, 2} {
+		header := withHistory.headers[6+i]
+		test.That(t, header.SequenceNumber, test.ShouldEqual, sequenceNumber)
+		test.That(t, header.SSRC, test.ShouldEqual, 1)
+	}
+
+	sent, err = track.retransmit(2, []uint16{65535})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldEqual, 0)
+	test.That(t, withoutHistory.headers, test.ShouldHaveLength, 6)
+}
+
+func TestRetransmitRTX(t *testing.T) {
+	track := newtrackLocalStaticRTP([]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8}}, "video", "stream")
+	track.nackHistorySize = 4
+	feedback := []webrtc.RTCPFeedback{{Type: "nack"}, {Type: "nack", Parameter: "pli"}}
+	var rtxWriter, plainWriter fakeTrackLocalWriter
+	codec, err := track.Bind(&fakeTrackLocalContext{
+		id:          "rtx",
+		ssrc:        1,
+		writeStream: &rtxWriter,
+		codecs: []webrtc.RTPCodecParameters{
+			{
+				RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: feedback},
+				PayloadType:        96,
+			},
+			{
+				RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeTypeRTX, ClockRate: 90000, SDPFmtpLine: "apt=96"},
+				PayloadType:        97,
+			},
+		},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	// pion's NACK responder must not answer the NACKs of the binding as well.
+	test.That(t, codec.RTCPFeedback, test.ShouldResemble, []webrtc.RTCPFeedback{{Type: "nack", Parameter: "pli"}})
+	test.That(t, feedback, test.ShouldHaveLength, 2)
+	_, err = track.Bind(&fakeTrackLocalContext{id: "plain", ssrc: 2, writeStream: &plainWriter})
+	test.That(t, err, test.ShouldBeNil)
+
+	for i := 0; i < 3; i++ {
+		packet := &rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(65534 + i), Timestamp: 1000}, Payload: []byte{byte(i), 0xff}}
+		test.That(t, track.WriteRTP(packet), test.ShouldBeNil)
+	}
+
+	// RTX packets carry the original sequence number ahead of the payload.
+	sent, err := track.retransmit(1, []uint16{65535, 0})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldEqual, 2)
+	test.That(t, rtxWriter.headers, test.ShouldHaveLength, 5)
+	first, second := rtxWriter.headers[3], rtxWriter.headers[4]
+	test.That(t, first.SSRC, test.ShouldNotEqual, 1)
+	test.That(t, second.SSRC, test.ShouldEqual, first.SSRC)
+	test.That(t, first.PayloadType, test.ShouldEqual, 97)
+	test.That(t, first.Timestamp, test.ShouldEqual, 1000)
+	test.That(t, second.SequenceNumber, test.ShouldEqual, first.SequenceNumber+1)
+	test.That(t, rtxWriter.payloads[3], test.ShouldResemble, []byte{0xff, 0xff, 1, 0xff})
+	test.That(t, rtxWriter.payloads[4], test.ShouldResemble, []byte{0x00, 0x00, 2, 0xff})
+
+	// peers without RTX are sent the packets as they were first sent.
+	sent, err = track.retransmit(2, []uint16{65535})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldEqual, 1)
+	test.That(t, plainWriter.headers, test.ShouldHaveLength, 4)
+	test.That(t, plainWriter.headers[3].SSRC, test.ShouldEqual, 2)
+	test.That(t, plainWriter.headers[3].SequenceNumber, test.ShouldEqual, 65535)
+	test.That(t, plainWriter.payloads[3], test.ShouldResemble, []byte{1, 0xff})
+}
+
+type fakeTrackLocalContext struct {
+	webrtc.TrackLocalContext
+	id          string
+	ssrc        webrtc.SSRC
+	writeStream webrtc.TrackLocalWriter
+	// codecs are the negotiated codecs, VP8 alone if not set.
+	codecs []webrtc.RTPCodecParameters
+}
+
+func (c *fakeTrackLocalContext) CodecParameters() []webrtc.RTPCodecParameters {
+	if c.codecs != nil {
+		return c.codecs
+	}
+	return []webrtc.RTPCodecParameters{{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+		PayloadType:        96,
+	}}
+}
+
+func (c *fakeTrackLocalContext) SSRC() webrtc.SSRC                    { return c.ssrc }
+func (c *fakeTrackLocalContext) WriteStream() webrtc.TrackLocalWriter { return c.writeStream }
+func (c *fakeTrackLocalContext) ID() string                           { return c.id }
+
+func TestBindHandoff(t *testing.T) {
+	codecs := []webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8}}
+	full := newVideoTrackLocalStaticSample(codecs, "video", "full", "stream", newMediaClock(), DefaultNACKHistorySize, 0)
+	thumbnail := newVideoTrackLocalStaticSample(codecs, "video", "thumbnail", "stream", newMediaClock(), DefaultNACKHistorySize, 0)
+	test.That(t, thumbnail.RID(), test.ShouldEqual, "thumbnail")
+
+	var writer fakeTrackLocalWriter
+	ctx := &fakeTrackLocalContext{id: "peer", ssrc: 1, writeStream: &writer}
+	_, err := full.Bind(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, full.WriteData(0, []byte{1, 2, 3}, 0, time.Now()), test.ShouldBeNil)
+
+	// switching tracks the way RTPSender.ReplaceTrack does continues the peer's RTP stream.
+	handoff, ok := full.handoff(1)
+	test.That(t, ok, test.ShouldBeTrue)
+	thumbnail.expectHandoff(1, handoff)
+	test.That(t, full.Unbind(ctx), test.ShouldBeNil)
+	_, err = thumbnail.Bind(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, thumbnail.WriteData(0, []byte{4, 5, 6}, 0, time.Now()), test.ShouldBeNil)
+
+	test.That(t, writer.headers, test.ShouldHaveLength, 2)
+	test.That(t, writer.headers[1].SSRC, test.ShouldEqual, 1)
+	test.That(t, writer.headers[1].SequenceNumber, test.ShouldEqual, writer.headers[0].SequenceNumber+1)
+
+	// packets sent before the switch can still be retransmitted.
+	sent, err := thumbnail.retransmit(1, []uint16{writer.headers[0].SequenceNumber})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldEqual, 1)
+
+	// a peer bound without a handoff starts a new RTP stream.
+	test.That(t, thumbnail.Unbind(ctx), test.ShouldBeNil)
+	_, err = thumbnail.Bind(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	thumbnail.rtpTrack.mu.RLock()
+	test.That(t, thumbnail.rtpTrack.bindings[0].packetizer, test.ShouldNotEqual, handoff.packetizer)
+	thumbnail.rtpTrack.mu.RUnlock()
+}
+
+func TestMutedBindings(t *testing.T) {
+	track := newVideoTrackLocalStaticSample(
+		[]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8}}, "video", "", "stream", newMediaClock(), 0, 0)
+	var first, second fakeTrackLocalWriter
+	_, err := track.Bind(&fakeTrackLocalContext{id: "first", ssrc: 1, writeStream: &first})
+	test.That(t, err, test.ShouldBeNil)
+	_, err = track.Bind(&fakeTrackLocalContext{id: "second", ssrc: 2, writeStream: &second})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, track.setMuted(3, true), test.ShouldNotBeNil)
+
+	test.That(t, track.setMuted(1, true), test.ShouldBeNil)
+	test.That(t, track.codecInUse(0), test.ShouldBeTrue)
+	test.That(t, track.WriteData(0, []byte{1, 2, 3}, 0, time.Now()), test.ShouldBeNil)
+	test.That(t, first.headers, test.ShouldHaveLength, 0)
+	test.That(t, second.headers, test.ShouldHaveLength, 1)
+
+	// the encoder is not needed once no peer receives the codec.
+	test.That(t, track.setMuted(2, true), test.ShouldBeNil)
+	test.That(t, track.codecInUse(0), test.ShouldBeFalse)
+
+	test.That(t, track.setMuted(2, false), test.ShouldBeNil)
+	test.That(t, track.WriteData(0, []byte{4, 5, 6}, 0, time.Now()), test.ShouldBeNil)
+	test.That(t, second.headers, test.ShouldHaveLength, 2)
+	test.That(t, second.headers[1].SequenceNumber, test.ShouldEqual, second.headers[0].SequenceNumber+1)
+
+	// relayed packets skipped while muted leave no gap in the sequence numbers.
+	relay := newtrackLocalStaticRTP([]webrtc.RTPCodecCapability{{MimeType: webrtc.MimeTypeVP8}}, "video", "stream")
+	var relayed fakeTrackLocalWriter
+	relay.bindings = []trackBinding{{ssrc: 1, writeStream: &relayed, maxTemporalLayer: -1, pendingMaxTemporalLayer: -1}}
+	test.That(t, relay.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 10}}), test.ShouldBeNil)
+	test.That(t, relay.setMuted(1, true), test.ShouldBeNil)
+	test.That(t, relay.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 11}}), test.ShouldBeNil)
+	test.That(t, relay.setMuted(1, false), test.ShouldBeNil)
+	test.That(t, relay.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 12}}), test.ShouldBeNil)
+	test.That(t, relayed.headers, test.ShouldHaveLength, 2)
+	test.That(t, relayed.headers[1].SequenceNumber, test.ShouldEqual, 11)
+}